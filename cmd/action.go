@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// isActionMode returns true when --action is given on the command line, as a
+// GitHub Action's entrypoint does. It has to be known before the options are
+// parsed, since the action's inputs set options too.
+func isActionMode(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--action" {
+			return true
+		}
+	}
+	return false
+}
+
+// getActionInputArgs returns the flags set by the inputs of a GitHub Action,
+// which the runner passes in INPUT_<NAME> variables, e.g. INPUT_MIN-DIFF-SCORE
+// for the min-diff-score input. Options which may be repeated take one value
+// per line.
+func getActionInputArgs(p *flags.Parser) ([]string, error) {
+	args := []string{}
+	for _, g := range p.Groups() {
+		for _, option := range g.Options() {
+			if option.LongName == "" || option.LongName == "action" {
+				continue
+			}
+			value := strings.TrimSpace(os.Getenv("INPUT_" + strings.ToUpper(option.LongName)))
+			if value == "" {
+				continue // inputs which aren't given are set, but empty
+			}
+			values := []string{}
+			for _, line := range strings.Split(value, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					values = append(values, line)
+				}
+			}
+			if option.Field().Type.Kind() != reflect.Slice {
+				values = []string{value}
+			}
+			optionArgs, err := getOptionArgs(option, values)
+			if err != nil {
+				return nil, fmt.Errorf("input %s: %w", option.LongName, err)
+			}
+			args = append(args, optionArgs...)
+		}
+	}
+	return args, nil
+}
+
+// appendToEnvFile appends to the file a GitHub Actions variable (e.g.
+// GITHUB_OUTPUT) names; outside of a runner, it is unset and nothing is written
+func appendToEnvFile(name, contents string) error {
+	path := os.Getenv(name)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(contents); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// markdownSummary describes the run as Markdown, for the job's summary page
+func markdownSummary(report *Report, stats *RunStats) string {
+	var sb strings.Builder
+	candidates := getCandidates(report)
+	fmt.Fprintf(&sb, "## Branch cleanup of %s\n\n", report.Base)
+	fmt.Fprintf(&sb, "%d branches analyzed, %d candidates, %d deleted.\n\n", stats.Analyzed, len(candidates), stats.Deleted)
+	if len(candidates) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("| Branch | Classification | Evidence | Deleted |\n")
+	sb.WriteString("| --- | --- | --- | --- |\n")
+	for _, b := range candidates {
+		deleted := "no"
+		if b.Deleted {
+			deleted = "yes"
+		} else if b.DeleteError != "" {
+			deleted = "failed: " + b.DeleteError
+		}
+		evidence := strings.TrimPrefix(describeDeletion(b), b.Classification)
+		evidence = strings.TrimPrefix(strings.TrimPrefix(evidence, " "), ": ")
+		fmt.Fprintf(&sb, "| `%s` | %s | %s | %s |\n", b.Branch, b.Classification, markdownEscape(evidence), markdownEscape(deleted))
+	}
+	return sb.String()
+}
+
+// markdownEscape keeps text within its table cell
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// writeActionOutputs writes the report to a file, and sets the action's
+// outputs and job summary: the candidates and deleted counts, and the report's path
+func writeActionOutputs(report *Report, stats *RunStats) error {
+	dir := os.Getenv("RUNNER_TEMP")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	reportPath := filepath.Join(dir, "branch-cleanup-report.json")
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	outputs := fmt.Sprintf("candidates=%d\ndeleted=%d\nreport=%s\n", len(getCandidates(report)), stats.Deleted, reportPath)
+	if err := appendToEnvFile("GITHUB_OUTPUT", outputs); err != nil {
+		return err
+	}
+	return appendToEnvFile("GITHUB_STEP_SUMMARY", markdownSummary(report, stats))
+}
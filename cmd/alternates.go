@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// getAlternates returns the object directories the repository borrows objects
+// from, e.g. when it was cloned with --reference or --shared. Objects found in
+// an alternate aren't stored in the repository, so deleting its branches can't
+// free them.
+func getAlternates() ([]string, error) {
+	alternates := []string{}
+	path, err := getGitPath("objects/info/alternates")
+	if err != nil {
+		return nil, err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			// relative to the objects directory
+			line = filepath.Join(filepath.Dir(filepath.Dir(path)), line)
+		}
+		alternates = append(alternates, filepath.Clean(line))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("GIT_ALTERNATE_OBJECT_DIRECTORIES")) {
+		if dir != "" {
+			alternates = append(alternates, dir)
+		}
+	}
+	return alternates, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// analyzeBranch finds the base commit which best matches branch, and gathers
+// any further evidence about whether it was merged
+func analyzeBranch(baseRev, branch string) (*PotentialMerge, error) {
+	potentialMerged, err := findMerged(baseRev, branch)
+	if err != nil || potentialMerged == nil {
+		return potentialMerged, err
+	}
+	if potentialMerged.MatchedSha != "" {
+		potentialMerged.TreeIdentical, err = isTreeIdentical(branchRef(branch), potentialMerged.MatchedSha)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !potentialMerged.Merged {
+		potentialMerged.RebasedUpstream, err = getRebasedUpstream(baseRev, branch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return potentialMerged, nil
+}
+
+// countUnmergedCommits returns the number of commits on the branch which aren't reachable from the base
+func countUnmergedCommits(baseRev, branch string) (int, error) {
+	count, err := runCommandTrimmedOutput("git", "rev-list", "--count", branchRef(branch), "--not", baseRev, "--")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(count)
+}
+
+// isTreeIdentical returns true when both commits have the same tree oid, which
+// is the strongest possible evidence that the branch's contents were merged
+func isTreeIdentical(a, b string) (bool, error) {
+	treeA, err := getGitRevParse(a + "^{tree}")
+	if err != nil {
+		return false, err
+	}
+	treeB, err := getGitRevParse(b + "^{tree}")
+	if err != nil {
+		return false, err
+	}
+	return treeA == treeB, nil
+}
+
+func getUpstream(branch string) (string, error) {
+	return runCommandTrimmedOutput("git", "for-each-ref", "--format=%(upstream)", branchRef(branch))
+}
+
+// isAncestor returns true when commit a is reachable from b
+func isAncestor(a, b string) bool {
+	return runCommand("git", "merge-base", "--is-ancestor", a, b) == nil
+}
+
+// getCherry returns one line per commit of head which is not an ancestor of
+// upstream, prefixed with "-" when an equivalent patch exists in upstream, or "+" otherwise
+func getCherry(upstream, head string) ([]string, error) {
+	lines, err := runCommandSplitLines("git", "cherry", upstream, head)
+	if err != nil {
+		return nil, err
+	}
+	cherry := []string{}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			cherry = append(cherry, line)
+		}
+	}
+	return cherry, nil
+}
+
+// getRebasedUpstream detects a branch which was rebased and force-pushed, but
+// not merged: its upstream has different commits with the same patches, and
+// still has work which isn't in the base. It returns the upstream ref in that
+// case, or "" otherwise.
+func getRebasedUpstream(baseRev, branch string) (string, error) {
+	upstream, err := getUpstream(branch)
+	if err != nil || upstream == "" {
+		return "", err
+	}
+	upstreamSha, err := getGitRevParse(upstream)
+	if err != nil {
+		return "", nil // upstream is gone
+	}
+	branchSha, err := getGitRevParse(branchRef(branch))
+	if err != nil {
+		return "", err
+	}
+	if upstreamSha == branchSha || isAncestor(upstreamSha, branchSha) || isAncestor(branchSha, upstreamSha) {
+		return "", nil // not rewritten, just ahead or behind
+	}
+
+	// every commit on the branch must have an equivalent in the upstream
+	localCherry, err := getCherry(upstreamSha, branchSha)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range localCherry {
+		if !strings.HasPrefix(line, "-") {
+			return "", nil
+		}
+	}
+
+	// and the upstream must still have work which isn't in the base
+	upstreamCherry, err := getCherry(baseRev, upstreamSha)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range upstreamCherry {
+		if strings.HasPrefix(line, "+") {
+			return upstream, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeletedTip is a branch tip which the deletion log recorded as it was deleted
+type DeletedTip struct {
+	Branch string
+	Tip    string
+	Reason string // see describeDeletion
+}
+
+// landedDeletions are the reasons (see describeDeletion) a branch is deleted
+// for once its contents are part of the base
+var landedDeletions = map[string]bool{
+	classMerged:            true,
+	classTrackingDuplicate: true,
+	classMatched:           true,
+	classRevert:            true,
+	classUpstreamed:        true,
+}
+
+// getDeletedTips returns the tips recorded in the reflog of deletionLogRef,
+// most recently deleted first. Tips which git gc pruned since are left out.
+func getDeletedTips() ([]*DeletedTip, error) {
+	if _, err := getGitRevParse(deletionLogRef); err != nil {
+		return nil, nil // nothing was deleted yet
+	}
+	// each entry's subject is "branch-cleanup: delete <branch>: <reason>"; the tip is
+	// its trailer, or its parent for entries logged before the tip was a trailer
+	lines, err := runCommandSplitLines("git", "log", "--walk-reflogs", "--format=%(trailers:key="+deletedTipTrailer+",valueonly,separator=)%x00%P%x00%gs", deletionLogRef, "--")
+	if err != nil {
+		return nil, err
+	}
+	tips := []*DeletedTip{}
+	for _, line := range lines {
+		fields := strings.SplitN(strings.TrimSpace(line), "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		tip, subject := fields[0], fields[2]
+		if tip == "" {
+			tip = fields[1]
+		}
+		branch, reason, ok := strings.Cut(strings.TrimPrefix(subject, "branch-cleanup: delete "), ": ")
+		if tip == "" || !ok {
+			continue
+		}
+		if _, err := getGitRevParse(tip + "^{commit}"); err != nil {
+			continue
+		}
+		tips = append(tips, &DeletedTip{Branch: branch, Tip: tip, Reason: reason})
+	}
+	return tips, nil
+}
+
+// getMergedAnchors returns the deleted tips whose contents landed in the base,
+// which the branches merged into them before they were deleted landed with.
+// Branches which exist again (e.g. after rollback) are left out, since their
+// deletion was undone.
+func getMergedAnchors() ([]*DeletedTip, error) {
+	tips, err := getDeletedTips()
+	if err != nil {
+		return nil, err
+	}
+	anchors := []*DeletedTip{}
+	seen := map[string]bool{}
+	for _, t := range tips {
+		classification, _, _ := strings.Cut(t.Reason, " ")
+		classification = strings.TrimSuffix(classification, ":") // e.g. "upstreamed: every commit ..."
+		if seen[t.Tip] || !landedDeletions[classification] {
+			continue
+		}
+		if _, err := getGitRevParse(branchRef(t.Branch)); err == nil {
+			continue
+		}
+		seen[t.Tip] = true
+		anchors = append(anchors, t)
+	}
+	return anchors, nil
+}
+
+// markMergedIntoDeleted reclassifies unmerged branches which were merged into
+// a branch which was deleted once it landed: e.g. B merged into A, and A squash
+// merged into the base, so B matches nothing in the base itself
+func markMergedIntoDeleted(report *Report) error {
+	unmerged := map[string]*BranchReport{}
+	for _, b := range report.Branches {
+		switch b.Classification {
+		case classUnmerged, classPotential, classUnknown, classUpstreamGone:
+			unmerged[b.Branch] = b
+		}
+	}
+	if len(unmerged) == 0 {
+		return nil
+	}
+	anchors, err := getMergedAnchors()
+	if err != nil {
+		return err
+	}
+	for _, anchor := range anchors {
+		lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname)", "--merged", anchor.Tip, branchPrefix)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			b, ok := unmerged[strings.TrimPrefix(strings.TrimSpace(line), branchPrefix)]
+			if !ok {
+				continue
+			}
+			b.Classification = classMergedIntoDeleted
+			b.Reason = fmt.Sprintf(T("merged into %s (at %s), which was deleted as %s"), anchor.Branch, anchor.Tip, anchor.Reason)
+			delete(unmerged, b.Branch)
+		}
+	}
+	return nil
+}
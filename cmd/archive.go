@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// archiveBranchPrefix is where --archive-rename moves branches, under the date
+// they were archived, e.g. archive/2024-01-02/feature/foo. Branches archived
+// there are never analyzed, only purged by purge-archive.
+const archiveBranchPrefix = "archive/"
+
+const archiveDateFormat = "2006-01-02"
+
+// archivedBranchName returns the name --archive-rename gives a branch archived at archivedAt
+func archivedBranchName(branchName string, archivedAt time.Time) string {
+	return archiveBranchPrefix + archivedAt.Format(archiveDateFormat) + "/" + branchName
+}
+
+// parseArchivedBranch returns when the branch was archived, and its name
+// before, for the names archivedBranchName gives; other branches under
+// archive/ are the user's own
+func parseArchivedBranch(branch string) (time.Time, string, bool) {
+	date, name, ok := strings.Cut(strings.TrimPrefix(branch, archiveBranchPrefix), "/")
+	if !strings.HasPrefix(branch, archiveBranchPrefix) || !ok || name == "" {
+		return time.Time{}, "", false
+	}
+	archivedAt, err := time.ParseInLocation(archiveDateFormat, date, time.Local)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return archivedAt, name, true
+}
+
+func isArchivedBranch(branch string) bool {
+	_, _, ok := parseArchivedBranch(branch)
+	return ok
+}
+
+// archiveBranch renames the branch to archived (see archivedBranchName)
+// instead of deleting it, so it stays an ordinary branch (with its reflog and
+// config) until purged
+func archiveBranch(branchName, archived string) error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	infof(T("renaming branch %s to %s\n"), branchName, archived)
+	return renameBranch(branchName, archived)
+}
+
+// renameBranch renames a branch, keeping its reflog and config where git can
+func renameBranch(from, to string) error {
+	if refNamespace != "" {
+		// git branch only works outside of namespaces
+		tip, err := getGitRevParse(branchRef(from))
+		if err != nil {
+			return err
+		}
+		if err := runCommand("git", "update-ref", branchRef(to), tip, ""); err != nil {
+			return err
+		}
+		return runCommand("git", "update-ref", "-d", branchRef(from), tip)
+	}
+	_, stderr, err := runCommandWithStderr("git", "branch", "-m", "--", from, to)
+	if err != nil && stderr != "" {
+		return fmt.Errorf("%s", strings.TrimPrefix(strings.SplitN(stderr, "\n", 2)[0], "fatal: "))
+	}
+	return err
+}
+
+// archiveRefPrefix is where --archive keeps deleted branches' tips, as
+// refs/archive/<branch>/<tip>, so every deletion of a name is kept
+const archiveRefPrefix = "refs/archive/"
+
+// archiveTip keeps the branch's tip under archiveRefPrefix, or with asTag in
+// an archive/<date>/<branch> tag, so its commits are never pruned once the
+// branch is deleted, though branch listings no longer show it
+func archiveTip(branchName string, asTag bool) error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	tip, err := getGitRevParse(branchRef(branchName))
+	if err != nil {
+		return err
+	}
+	ref := refNamespace + archiveRefPrefix + branchName + "/" + tip
+	if asTag {
+		ref = refNamespace + "refs/tags/" + archivedBranchName(branchName, time.Now())
+		// an older tag of the same name is never replaced, since it may be all that is left of its commits
+		if archived, err := getGitRevParse(ref); err == nil && archived != tip {
+			return fmt.Errorf("%s already archives %s", strings.TrimPrefix(ref, refNamespace), archived)
+		}
+	}
+	infof(T("archiving branch %s as %s\n"), branchName, strings.TrimPrefix(ref, refNamespace))
+	return runCommand("git", "update-ref", ref, tip)
+}
+
+// findArchivedTip returns the latest committed of the tips --archive kept of
+// branch, or ""
+func findArchivedTip(branch string) (string, error) {
+	refPrefix := refNamespace + archiveRefPrefix + branch + "/"
+	tagPrefix := refNamespace + "refs/tags/" + archiveBranchPrefix
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--sort=-creatordate", "--format=%(refname) %(objectname)", refPrefix, tagPrefix)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		ref, tip, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if sha := strings.TrimPrefix(ref, refPrefix); sha != ref && !strings.Contains(sha, "/") {
+			return tip, nil // not refs/archive/<branch>/<other>/<tip>, which archives another branch
+		}
+		if _, name, ok := parseArchivedBranch(strings.TrimPrefix(ref, refNamespace+"refs/tags/")); ok && name == branch {
+			return tip, nil
+		}
+	}
+	return "", nil
+}
+
+// purgeArchive deletes the branches archived more than days ago
+func purgeArchive(days int) error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	branches, err := getBranches()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	purged := 0
+	for _, branch := range branches {
+		archivedAt, _, ok := parseArchivedBranch(branch)
+		if !ok || !archivedAt.Before(cutoff) {
+			continue
+		}
+		tip, err := getGitRevParse(branchRef(branch))
+		if err != nil {
+			return err
+		}
+		if err := deleteBranch(branch); err != nil {
+			return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+		}
+		if err := logDeletion(branch, tip, "purged from the archive"); err != nil {
+			warnf(T("failed to log the deletion of %s: %v\n"), branch, err)
+		}
+		purged++
+	}
+	reportf(T("purged %d branches archived more than %d days ago\n"), purged, days)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// auditRepository returns warnings about repository states which make the
+// analysis unreliable, to be shown in the report header
+func auditRepository(useReplaceObjects bool) ([]string, error) {
+	warnings := []string{}
+
+	gitDir, err := getGitCommonDir()
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname)", "refs/replace/")
+	if err != nil {
+		return nil, err
+	}
+	numReplaced := 0
+	for _, line := range lines {
+		if line != "" {
+			numReplaced++
+		}
+	}
+	if numReplaced > 0 && useReplaceObjects {
+		warnings = append(warnings, fmt.Sprintf("%d replace refs exist under refs/replace/; they change history and merge-base results", numReplaced))
+	} else if numReplaced > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d replace refs exist under refs/replace/; they are being ignored (see --use-replace-objects)", numReplaced))
+	}
+	if pathExists(filepath.Join(gitDir, "info", "grafts")) {
+		warnings = append(warnings, "grafts are configured in info/grafts; they change history and merge-base results")
+	}
+
+	shallow, err := runCommandTrimmedOutput("git", "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return nil, err
+	}
+	if shallow == "true" {
+		warnings = append(warnings, "the repository is shallow; merges older than the shallow boundary cannot be found")
+	}
+
+	alternates, err := getAlternates()
+	if err != nil {
+		return nil, err
+	}
+	if len(alternates) > 0 {
+		warnings = append(warnings, "objects are borrowed from alternate object databases; they may disappear if the alternate is pruned")
+	}
+
+	return warnings, nil
+}
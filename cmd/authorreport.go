@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// useRemoteBranches switches the run to the branches of remote, by their
+// remote-tracking branches (so fetch first), compared against the remote's
+// default branch. Nothing is deleted afterwards, since none of the branches
+// are local.
+func (rs *runState) useRemoteBranches(remote string) error {
+	remoteBase, err := getRemoteBase(remote, rs.baseName)
+	if err != nil {
+		return err
+	}
+	if remoteBase == "" {
+		return fmt.Errorf("the default branch of %s is unknown; run git remote set-head %s --auto", remote, remote)
+	}
+	rs.baseRev, err = resolveBase(remoteBase)
+	if err != nil {
+		return err
+	}
+	rs.baseName = remoteBase
+
+	branchPrefix = remotePrefix + remote + "/"
+	branches, err := getBranches()
+	if err != nil {
+		return err
+	}
+	rs.branches = []string{}
+	for _, branch := range branches {
+		if branch != "HEAD" && remote+"/"+branch != remoteBase {
+			rs.branches = append(rs.branches, branch)
+		}
+	}
+	rs.deleteDisabled = fmt.Errorf("the branches of %s are only reported", remote)
+	return nil
+}
+
+// hasLanded returns true when a branch's contents are known to be part of the
+// base, so it is only left undeleted
+func hasLanded(classification string) bool {
+	switch classification {
+	case classMerged, classMatched, classRevert, classMergedIntoDeleted, classUpstreamed:
+		return true
+	}
+	return false
+}
+
+// AuthorSummary lists an author's merged branches which are yet to be deleted
+type AuthorSummary struct {
+	Author   string    `json:"author"`
+	Count    int       `json:"count"`
+	Oldest   time.Time `json:"oldest"` // when the oldest branch's tip was committed
+	Branches []string  `json:"branches"`
+}
+
+// summarizeByAuthor groups the branches by their tip's author, most branches first
+func summarizeByAuthor(branches []*BranchReport, tipTimes map[string]int64) ([]*AuthorSummary, error) {
+	authors, err := getBranchTipAuthors()
+	if err != nil {
+		return nil, err
+	}
+	byAuthor := map[string]*AuthorSummary{}
+	summaries := []*AuthorSummary{}
+	for _, b := range branches {
+		author := authors[b.Branch]
+		s, ok := byAuthor[author]
+		if !ok {
+			s = &AuthorSummary{Author: author}
+			byAuthor[author] = s
+			summaries = append(summaries, s)
+		}
+		s.Count++
+		s.Branches = append(s.Branches, b.Branch)
+		if tip := time.Unix(tipTimes[b.Branch], 0); s.Oldest.IsZero() || tip.Before(s.Oldest) {
+			s.Oldest = tip
+		}
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		return summaries[i].Author < summaries[j].Author
+	})
+	return summaries, nil
+}
+
+// ageInDays is how long ago t was, in whole days
+func ageInDays(t time.Time) int {
+	return int(time.Since(t) / (24 * time.Hour))
+}
+
+// showLandedReport lists the branches which were merged but not deleted yet,
+// and how old they are; with byAuthor, as each author's cleanup to-do, which
+// reads well when pasted into a chat
+func showLandedReport(report *Report, byAuthor bool) error {
+	landed := []*BranchReport{}
+	for _, b := range report.Branches {
+		if hasLanded(b.Classification) {
+			landed = append(landed, b)
+		}
+	}
+	tipTimes, err := getBranchTipTimes()
+	if err != nil {
+		return err
+	}
+
+	if !byAuthor {
+		switch {
+		case reportJSON:
+			return writeJSON(os.Stdout, landed)
+		case reportTable:
+			return writeReportTable(landed)
+		case reportTemplate != nil:
+			return writeFormatted(os.Stdout, landed)
+		}
+		for _, b := range landed {
+			reportf(T("%s: %s, %d days old\n"), b.Branch, b.Classification, ageInDays(time.Unix(tipTimes[b.Branch], 0)))
+		}
+		return nil
+	}
+
+	summaries, err := summarizeByAuthor(landed, tipTimes)
+	if err != nil {
+		return err
+	}
+	if reportJSON {
+		return writeJSON(os.Stdout, summaries)
+	}
+	for _, s := range summaries {
+		reportf(T("%s: %d merged branches, the oldest %d days old\n"), s.Author, s.Count, ageInDays(s.Oldest))
+		for _, branch := range s.Branches {
+			reportf(T("  %s (%d days)\n"), branch, ageInDays(time.Unix(tipTimes[branch], 0)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// backupRegexp matches names given to backup copies of a branch, e.g.
+// foo-backup, foo-old2, foo-copy, or foo.orig
+var backupRegexp = regexp.MustCompile(`^(.+?)(-backup|-bak|-old|-copy|\.orig)(-?[0-9]+)?$`)
+
+// getContainingBranches returns the local branches, other than branch itself, which contain its tip
+func getContainingBranches(branch string) ([]string, error) {
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname)", "--contains", branchRef(branch), branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	containing := []string{}
+	for _, line := range lines {
+		if other := strings.TrimPrefix(strings.TrimSpace(line), branchPrefix); other != "" && other != branch {
+			containing = append(containing, other)
+		}
+	}
+	return containing, nil
+}
+
+// markBackups reclassifies unmerged branches named like backups whose commits
+// are all contained in another local branch, i.e. nothing is lost by deleting them
+func markBackups(report *Report) error {
+	for _, b := range report.Branches {
+		switch b.Classification {
+		case classUnmerged, classPotential, classUnknown:
+		default:
+			continue
+		}
+		m := backupRegexp.FindStringSubmatch(b.Branch)
+		if m == nil {
+			continue
+		}
+		containing, err := getContainingBranches(b.Branch)
+		if err != nil {
+			return err
+		}
+		// backups of backups don't count, or two copies of the same commit would both be deleted
+		original := ""
+		for _, other := range containing {
+			if backupRegexp.MatchString(other) {
+				continue
+			}
+			if original == "" || other == m[1] {
+				original = other // preferring the branch the backup is named after
+			}
+		}
+		if original == "" {
+			continue
+		}
+		b.Classification = classBackup
+		b.Reason = fmt.Sprintf(T("contained in %s"), original)
+	}
+	return nil
+}
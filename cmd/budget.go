@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const progressFileName = "progress.json"
+
+// Progress records where a run stopped due to --limit or --time-budget, so the
+// next run can pick up from there
+type Progress struct {
+	LastAnalyzed string `json:"lastAnalyzed"`
+}
+
+func getProgressPath() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, progressFileName), nil
+}
+
+func loadProgress() (*Progress, error) {
+	path, err := getProgressPath()
+	if err != nil {
+		return nil, err
+	}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Progress{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var progress Progress
+	if err := json.Unmarshal(contents, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func saveProgress(progress *Progress) error {
+	path, err := getProgressPath()
+	if err != nil {
+		return err
+	}
+	if progress.LastAnalyzed == "" {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	contents, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0644)
+}
+
+// resumeAfter rotates the (sorted) branches so analysis starts with the first
+// branch after the one the previous budgeted run stopped at
+func resumeAfter(branches []string, lastAnalyzed string) []string {
+	if lastAnalyzed == "" {
+		return branches
+	}
+	for i, branch := range branches {
+		if branch > lastAnalyzed {
+			return append(append([]string{}, branches[i:]...), branches[:i]...)
+		}
+	}
+	return branches
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const commitCacheFileName = "commit-cache.json"
+
+// loadCommitCache seeds CommitDiffCache with the diffs stored by warm-cache.
+// Commits are immutable, so stored diffs never go stale; but replace refs can
+// change what a sha's diff is, so the cache is only used when they are ignored.
+func loadCommitCache() error {
+	path, err := getCommitCachePath()
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cache := map[string]*CommitDiff{}
+	if err := json.Unmarshal(contents, &cache); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if CommitDiffCache == nil {
+		CommitDiffCache = map[string]*CommitDiff{}
+	}
+	for sha, commitDiff := range cache {
+		CommitDiffCache[sha] = commitDiff
+	}
+	return nil
+}
+
+// saveCommitCache stores CommitDiffCache, replacing the file atomically since
+// a run may be reading it while warm-cache runs in the background (in any
+// clone, when the cache is shared)
+func saveCommitCache() error {
+	path, err := getCommitCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	contents, err := json.Marshal(CommitDiffCache)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), commitCacheFileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// warmCache computes the diff of every commit a run would compare: the base
+// commits since each branch diverged, and each branch's tip
+func warmCache(rs *runState) error {
+	if err := loadCommitCache(); err != nil {
+		return err
+	}
+	before := len(CommitDiffCache)
+
+	mergeBases := []string{}
+	for _, branch := range rs.branches {
+		ref := branchRef(branch)
+		mergeBase, err := getGitMergeBase(rs.baseRev, ref)
+		if err != nil {
+			continue // unrelated history
+		}
+		mergeBases = append(mergeBases, mergeBase)
+		branchCommits, err := getCommits(mergeBase, ref)
+		if err != nil {
+			return err
+		}
+		if len(branchCommits) > 0 {
+			if _, err := getCommitDiff(branchCommits[0]); err != nil {
+				return err
+			}
+		}
+	}
+	if len(mergeBases) == 0 {
+		return saveCommitCache()
+	}
+
+	// the base commits since any branch diverged are those which aren't
+	// reachable from the common ancestors of every merge-base
+	oldest, err := runCommandSplitLines(append([]string{"git", "merge-base", "--octopus", "--all"}, mergeBases...)...)
+	if err != nil {
+		return err
+	}
+	revs := []string{"git", "rev-list", rs.baseRev}
+	for _, commit := range oldest {
+		revs = append(revs, "^"+commit)
+	}
+	baseCommits, err := runCommandSplitLines(append(revs, "--")...)
+	if err != nil {
+		return err
+	}
+	for _, commit := range baseCommits {
+		if commit == "" {
+			continue
+		}
+		if _, err := getCommitDiff(commit); err != nil {
+			return err
+		}
+	}
+	if err := saveCommitCache(); err != nil {
+		return err
+	}
+	infof(T("cached %d new commits (%d in total)\n"), len(CommitDiffCache)-before, len(CommitDiffCache))
+	return nil
+}
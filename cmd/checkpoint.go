@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const checkpointFileName = "checkpoint.json"
+
+// Checkpoint records the tips of the branches an interactive run is about to
+// delete, so the whole batch can be restored with the rollback command
+type Checkpoint struct {
+	Time     time.Time          `json:"time"`
+	Branches []*CheckpointEntry `json:"branches"`
+}
+
+type CheckpointEntry struct {
+	Branch string `json:"branch"`
+	TipSha string `json:"tipSha"`
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// prompt asks a question on stderr (keeping stdout for the report), and returns
+// the lowercased first letter of the answer
+func prompt(format string, args ...interface{}) string {
+	fmt.Fprintf(os.Stderr, format, args...)
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(os.Stderr)
+		return "q" // stdin was closed
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "" {
+		return ""
+	}
+	return answer[:1]
+}
+
+func saveCheckpoint(branches []*BranchReport) error {
+	path, err := getStateFilePath(checkpointFileName)
+	if err != nil {
+		return err
+	}
+	checkpoint := &Checkpoint{Time: time.Now()}
+	for _, b := range branches {
+		tip, err := getGitRevParse(branchRef(b.Branch))
+		if err != nil {
+			return err
+		}
+		checkpoint.Branches = append(checkpoint.Branches, &CheckpointEntry{Branch: b.Branch, TipSha: tip})
+	}
+	return writeJSONFile(path, checkpoint)
+}
+
+// rollback recreates the branches deleted since the last interactive run's checkpoint
+func rollback() error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	path, err := getStateFilePath(checkpointFileName)
+	if err != nil {
+		return err
+	}
+	var checkpoint Checkpoint
+	if err := readJSONFile(path, &checkpoint); err != nil {
+		return err
+	}
+	if len(checkpoint.Branches) == 0 {
+		return fmt.Errorf("there is no checkpoint to roll back to")
+	}
+	for _, e := range checkpoint.Branches {
+		if err := restoreBranch(e.Branch, e.TipSha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
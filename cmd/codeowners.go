@@ -0,0 +1,134 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// codeownersPaths are where GitHub and GitLab look for CODEOWNERS, in order
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// Codeowners maps files to their owners; later rules take precedence
+type Codeowners []*codeownersRule
+
+// codeownersPatternRegexp converts a CODEOWNERS (i.e. gitignore style) pattern to a regexp
+func codeownersPatternRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var re strings.Builder
+	if !anchored {
+		re.WriteString("(^|.*/)")
+	} else {
+		re.WriteString("^")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dir {
+		re.WriteString("/.*$")
+	} else {
+		re.WriteString("(/.*)?$") // a pattern naming a directory owns everything in it
+	}
+	return regexp.Compile(re.String())
+}
+
+// loadCodeowners reads the CODEOWNERS file as of the base, or returns nil when there is none
+func loadCodeowners(baseRev string) (Codeowners, error) {
+	for _, path := range codeownersPaths {
+		contents, err := runCommandTrimmedOutput("git", "--no-pager", "show", baseRev+":"+path)
+		if err != nil {
+			continue
+		}
+		codeowners := Codeowners{}
+		for _, line := range strings.Split(contents, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "[") {
+				continue // blank, a comment, or a GitLab section header
+			}
+			pattern, err := codeownersPatternRegexp(fields[0])
+			if err != nil {
+				warnf(T("ignoring CODEOWNERS pattern %s: %v\n"), fields[0], err)
+				continue
+			}
+			owners := []string{}
+			for _, owner := range fields[1:] {
+				if strings.HasPrefix(owner, "#") {
+					break
+				}
+				owners = append(owners, owner)
+			}
+			codeowners = append(codeowners, &codeownersRule{pattern: pattern, owners: owners})
+		}
+		return codeowners, nil
+	}
+	return nil, nil
+}
+
+// ownersOf returns the owners of a file, from the last matching rule
+func (c Codeowners) ownersOf(file string) []string {
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].pattern.MatchString(file) {
+			return c[i].owners
+		}
+	}
+	return nil
+}
+
+// getBranchOwners returns the owners of all the files a branch changes, and
+// the files among them owned by any of wanted
+func getBranchOwners(codeowners Codeowners, baseRev, branch string, wanted []string) ([]string, []string, error) {
+	files, err := getBranchChangedFiles(baseRev, branch, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := map[string]bool{}
+	owners := []string{}
+	wantedFiles := []string{}
+	for _, file := range files {
+		fileOwners := codeowners.ownersOf(file)
+		for _, owner := range fileOwners {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+		if hasAnyOwner(fileOwners, wanted) {
+			wantedFiles = append(wantedFiles, file)
+		}
+	}
+	sort.Strings(owners)
+	return owners, wantedFiles, nil
+}
+
+func hasAnyOwner(owners, wanted []string) bool {
+	for _, owner := range owners {
+		for _, w := range wanted {
+			if strings.EqualFold(owner, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
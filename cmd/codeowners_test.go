@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeownersPatternRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		match   bool
+	}{
+		{"*.js", "app.js", true},
+		{"*.js", "web/src/app.js", true},
+		{"*.js", "app.jsx", false},
+		{"*", "any/file", true},
+		{"/docs/", "docs/guide.md", true},
+		{"/docs/", "docs/deep/guide.md", true},
+		{"/docs/", "web/docs/guide.md", false},
+		{"/docs/", "docs", false}, // a file, not the directory
+		{"docs/", "web/docs/guide.md", true},
+		{"docs", "web/docs/guide.md", true}, // owns everything in a directory of that name
+		{"docs", "docs.md", false},
+		{"apps/web", "apps/web/index.ts", true},
+		{"apps/web", "src/apps/web/index.ts", false}, // anchored, since it has a slash
+		{"/README.md", "README.md", true},
+		{"/README.md", "docs/README.md", false},
+		{"**/logs", "logs/today", true},
+		{"**/logs", "var/app/logs/today", true},
+		{"docs/**/*.md", "docs/guide.md", true},
+		{"docs/**/*.md", "docs/a/b/guide.md", true},
+		{"docs/**/*.md", "web/docs/guide.md", false},
+		{"apps/**", "apps/web/index.ts", true},
+		{"apps/*", "apps/web/index.ts", true}, // apps/web is a directory it matches
+		{"a?c.txt", "abc.txt", true},
+		{"a?c.txt", "ac.txt", false},
+		{"a?c.txt", "a/c.txt", false},
+		{"c++/a.h", "c++/a.h", true}, // regexp characters are literal
+		{"c++/a.h", "cc/a.h", false},
+		{"v1.2", "v1x2", false},
+	}
+	for _, test := range tests {
+		re, err := codeownersPatternRegexp(test.pattern)
+		if err != nil {
+			t.Errorf("codeownersPatternRegexp(%q): %v", test.pattern, err)
+			continue
+		}
+		if got := re.MatchString(test.file); got != test.match {
+			t.Errorf("%q matches %q: %v, expected %v (regexp %s)", test.pattern, test.file, got, test.match, re)
+		}
+	}
+}
+
+func TestLoadCodeowners(t *testing.T) {
+	r := newTestRepo(t)
+	r.git("checkout", "--quiet", "main")
+	r.write("CODEOWNERS", `# the owners of everything
+*        @org/everyone
+
+[Docs]
+/docs/   @org/docs  @writer # docs need a second review
+*.go     @org/go
+/cmd/legacy.go
+`)
+	r.git("add", "CODEOWNERS")
+	r.git("commit", "--quiet", "-m", "Add CODEOWNERS")
+	r.enter()
+
+	codeowners, err := loadCodeowners("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		file   string
+		owners []string
+	}{
+		{"README.md", []string{"@org/everyone"}},
+		{"docs/guide.md", []string{"@org/docs", "@writer"}},
+		{"docs/gen.go", []string{"@org/go"}}, // the last matching rule wins
+		{"cmd/main.go", []string{"@org/go"}},
+		{"cmd/legacy.go", []string{}}, // a rule without owners leaves it unowned
+	}
+	for _, test := range tests {
+		if got := codeowners.ownersOf(test.file); !reflect.DeepEqual(got, test.owners) {
+			t.Errorf("ownersOf(%q) = %q, expected %q", test.file, got, test.owners)
+		}
+	}
+}
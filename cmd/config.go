@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// getBranchConfigNames returns the branches which have a [branch "<name>"]
+// section in the repository's own config
+func getBranchConfigNames() ([]string, error) {
+	lines, err := runCommandSplitLines("git", "config", "--local", "--name-only", "--get-regexp", `^branch\.`)
+	if err != nil {
+		return nil, nil // git config exits 1 when nothing matches
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, line := range lines {
+		key := strings.TrimSpace(line)
+		// branch.<name>.<variable>; the name may itself contain dots
+		first, last := strings.Index(key, "."), strings.LastIndex(key, ".")
+		if first == last {
+			continue // e.g. branch.autoSetupMerge
+		}
+		if name := key[first+1 : last]; !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// removeOrphanedBranchConfig removes the config sections of branches which no
+// longer exist; when only is non-nil, just the sections of those branches are
+// considered. It returns the branches whose config was removed.
+func removeOrphanedBranchConfig(only map[string]bool) ([]string, error) {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return nil, err
+	}
+	names, err := getBranchConfigNames()
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	if refNamespace != "" {
+		// branch config belongs to the branches outside of any namespace
+		return removed, nil
+	}
+	for _, name := range names {
+		if only != nil && !only[name] {
+			continue
+		}
+		if _, err := getGitRevParse(branchRef(name)); err == nil {
+			continue // the branch still exists
+		}
+		if err := runCommand("git", "config", "--local", "--remove-section", "branch."+name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
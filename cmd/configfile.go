@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// repoConfigFileName is the config file a repo can check in, so a team's
+// policy applies to everyone cleaning up its branches
+const repoConfigFileName = ".git-branch-cleanup.yaml"
+
+// repoConfigFileOptions are the options a repo's config file can set: those
+// which only change what is reported, or make fewer branches deletable, since
+// anyone able to push to the repo could otherwise make everyone cleaning up
+// there delete more (e.g. with min-diff-score: 0, or remote: origin). The
+// rest can only be set on the command line or in the user's config file.
+var repoConfigFileOptions = map[string]bool{
+	"verbose":       true,
+	"perfect":       true,
+	"lang":          true,
+	"log-format":    true,
+	"stats":         true,
+	"max-procs":     true,
+	"output":        true,
+	"sort":          true,
+	"max-load":      true,
+	"sample-order":  true,
+	"group-by":      true,
+	"limit":         true,
+	"time-budget":   true,
+	"match-window":  true,
+	"graph-format":  true,
+	"heatmap-depth": true,
+	"touches":       true,
+	"owned-by":      true,
+	"scope":         true,
+	"strict":        true,
+	"format":        true,
+	"no-truncate":   true,
+	"interactive":   true,
+	"dry-run":       true,
+	"protect":       true,
+	"shared-cache":  true,
+	"by-author":     true,
+}
+
+// getGlobalConfigPath returns where the user's config file lives, following
+// the XDG base directory spec
+func getGlobalConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "git-branch-cleanup", "config.yaml")
+}
+
+// parseConfigFile reads the flat YAML a config file is written in: one key
+// per line, whose value is a scalar, a [flow, list], or a block list of
+// "- item" lines. Anything nested deeper is rejected rather than misread.
+func parseConfigFile(path string) ([]string, map[string][]string, error) {
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := []string{} // in the order given, so options are applied like flags would be
+	values := map[string][]string{}
+	var listKey string
+	for i, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return nil, nil, fmt.Errorf("line %d: list item without a key", i+1)
+			}
+			values[listKey] = append(values[listKey], unquoteYAML(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+			continue
+		}
+		if line != trimmed {
+			return nil, nil, fmt.Errorf("line %d: nested values are not supported", i+1)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected key: value", i+1)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if _, seen := values[key]; seen {
+			return nil, nil, fmt.Errorf("line %d: %s is given twice", i+1, key)
+		}
+		keys = append(keys, key)
+		listKey = ""
+		switch {
+		case value == "":
+			listKey = key
+			values[key] = []string{}
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			values[key] = []string{}
+			for _, item := range splitYAMLFlowList(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")) {
+				if item = strings.TrimSpace(item); item != "" {
+					values[key] = append(values[key], unquoteYAML(item))
+				}
+			}
+		default:
+			values[key] = []string{unquoteYAML(value)}
+		}
+	}
+	return keys, values, nil
+}
+
+// stripYAMLComment drops a # comment, unless it is quoted
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitYAMLFlowList splits the items of a [flow, list] at commas which aren't quoted
+func splitYAMLFlowList(list string) []string {
+	items := []string{}
+	var quote rune
+	start := 0
+	for i, r := range list {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, list[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, list[start:])
+}
+
+func unquoteYAML(value string) string {
+	if len(value) < 2 || value[len(value)-1] != value[0] {
+		return value
+	}
+	switch value[0] {
+	case '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	case '"':
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted // e.g. "tab\there"
+		}
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// configFileArgs turns a config file into the flags it stands for, which are
+// parsed ahead of the command line's so that the command line wins. Keys are
+// the long names of the options.
+func configFileArgs(p *flags.Parser, path string, global bool) ([]string, error) {
+	keys, values, err := parseConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	for _, key := range keys {
+		option := p.FindOptionByLongName(key)
+		if option == nil {
+			return nil, fmt.Errorf("unknown option %s", key)
+		}
+		if !global && !repoConfigFileOptions[key] {
+			return nil, fmt.Errorf("%s can only be set on the command line or in %s", key, getGlobalConfigPath())
+		}
+		optionArgs, err := getOptionArgs(option, values[key])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, optionArgs...)
+	}
+	return args, nil
+}
+
+// getOptionArgs returns the flags setting an option to the values given
+func getOptionArgs(option *flags.Option, values []string) ([]string, error) {
+	if option.Field().Type.Kind() == reflect.Bool {
+		if len(values) != 1 {
+			return nil, fmt.Errorf("%s must be true or false", option.LongName)
+		}
+		// a flag can only be set, so false leaves the option at its default (see negateConfigFlags)
+		if isTruthy(values[0]) {
+			return []string{"--" + option.LongName}, nil
+		}
+		return nil, nil
+	}
+	args := []string{}
+	for _, value := range values {
+		args = append(args, "--"+option.LongName+"="+value)
+	}
+	return args, nil
+}
+
+// getConfigFileArgs returns the flags set by the user's config file, then by
+// the repo's, so the repo's policy overrides personal defaults. Options which
+// may be repeated (e.g. --touches) accumulate instead.
+func getConfigFileArgs(p *flags.Parser) ([]string, error) {
+	args := []string{}
+	paths := []string{getGlobalConfigPath()}
+	if topLevel, err := getGitTopLevel(); err == nil {
+		paths = append(paths, filepath.Join(topLevel, repoConfigFileName))
+	}
+	for i, path := range paths {
+		if path == "" {
+			continue
+		}
+		fileArgs, err := configFileArgs(p, path, i == 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		args = append(args, fileArgs...)
+	}
+	return args, nil
+}
+
+// negateConfigFlags drops the command line's --no-<flag> for each boolean
+// option, and the --<flag> a config file (or action input) set it with, so a
+// flag set by a config file can be turned off again
+func negateConfigFlags(p *flags.Parser, configArgs, args []string) ([]string, []string) {
+	negated := map[string]bool{}
+	rest := []string{}
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if name := strings.TrimPrefix(arg, "--no-"); name != arg && p.FindOptionByLongName(arg[2:]) == nil {
+			if option := p.FindOptionByLongName(name); option != nil && option.Field().Type.Kind() == reflect.Bool {
+				negated[name] = true
+				continue
+			}
+		}
+		rest = append(rest, arg)
+	}
+	if len(negated) == 0 {
+		return configArgs, args
+	}
+	kept := []string{}
+	for _, arg := range configArgs {
+		if !negated[strings.TrimPrefix(arg, "--")] {
+			kept = append(kept, arg)
+		}
+	}
+	return kept, rest
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// newTestParser returns a parser of the options into progOpts, like main's
+func newTestParser(t *testing.T, progOpts *opts) *flags.Parser {
+	t.Helper()
+	p := flags.NewNamedParser("", flags.PassDoubleDash|flags.PassAfterNonOption)
+	if _, err := p.AddGroup("options", "", progOpts); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		keys     []string
+		values   map[string][]string
+		err      string
+	}{
+		{
+			name:     "scalars",
+			contents: "---\nmin-diff-score: 0.95\nstrip-emoji: true\r\n\nlang:de\n",
+			keys:     []string{"min-diff-score", "strip-emoji", "lang"},
+			values:   map[string][]string{"min-diff-score": {"0.95"}, "strip-emoji": {"true"}, "lang": {"de"}},
+		},
+		{
+			name:     "comments",
+			contents: "# the team's policy\nsort: age # oldest first\nformat: '{{.Branch}} # {{.Reason}}'\nbase: main#1\n",
+			keys:     []string{"sort", "format", "base"},
+			values:   map[string][]string{"sort": {"age"}, "format": {"{{.Branch}} # {{.Reason}}"}, "base": {"main#1"}},
+		},
+		{
+			name:     "quoting",
+			contents: "a: \"double\"\nb: 'single'\nc: 'it''s'\nd: \"tab\\there\"\ne: \"unbalanced\nf: \"\"\n",
+			keys:     []string{"a", "b", "c", "d", "e", "f"},
+			values:   map[string][]string{"a": {"double"}, "b": {"single"}, "c": {"it's"}, "d": {"tab\there"}, "e": {`"unbalanced`}, "f": {""}},
+		},
+		{
+			name:     "flow list",
+			contents: "protect: [release/*, \"a, b\", 'c', ]\nempty: []\n",
+			keys:     []string{"protect", "empty"},
+			values:   map[string][]string{"protect": {"release/*", "a, b", "c"}, "empty": {}},
+		},
+		{
+			name:     "block list",
+			contents: "protect:\n  - release/*   # releases\n  - 'hotfix/*'\n  -\ntouches:\n- services/\n",
+			keys:     []string{"protect", "touches"},
+			values:   map[string][]string{"protect": {"release/*", "hotfix/*", ""}, "touches": {"services/"}},
+		},
+		{
+			name:     "duplicate key",
+			contents: "sort: age\nsort: name\n",
+			err:      "line 2: sort is given twice",
+		},
+		{
+			name:     "list item without a key",
+			contents: "- release/*\n",
+			err:      "line 1: list item without a key",
+		},
+		{
+			name:     "list item after a scalar",
+			contents: "sort: age\n  - name\n",
+			err:      "line 2: list item without a key",
+		},
+		{
+			name:     "nested",
+			contents: "scores:\n  diff: 0.9\n",
+			err:      "line 2: nested values are not supported",
+		},
+		{
+			name:     "no value",
+			contents: "sort age\n",
+			err:      "line 1: expected key: value",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, values, err := parseConfigFile(writeConfigFile(t, test.contents))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("parseConfigFile() = %v, expected %s", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(keys, test.keys) {
+				t.Errorf("keys = %q, expected %q", keys, test.keys)
+			}
+			if !reflect.DeepEqual(values, test.values) {
+				t.Errorf("values = %q, expected %q", values, test.values)
+			}
+		})
+	}
+}
+
+func TestConfigFileArgs(t *testing.T) {
+	type configFileTest struct {
+		name     string
+		contents string
+		global   bool
+		args     []string
+		err      string
+	}
+	tests := []configFileTest{
+		{
+			name:     "global",
+			contents: "min-diff-score: 0.95\nremote: origin\nstrip-emoji: true\naccept-risk: false\nprotect: [release/*, develop]\n",
+			global:   true,
+			args:     []string{"--min-diff-score=0.95", "--remote=origin", "--strip-emoji", "--protect=release/*", "--protect=develop"},
+		},
+		{
+			name:     "repo",
+			contents: "sort: age\nprotect:\n  - release/*\ndry-run: yes\n",
+			args:     []string{"--sort=age", "--protect=release/*", "--dry-run"},
+		},
+		{
+			name:     "unknown key",
+			contents: "min-score: 0.5\n",
+			global:   true,
+			err:      "unknown option min-score",
+		},
+		{
+			name:     "bool list",
+			contents: "gc: [true, false]\n",
+			global:   true,
+			err:      "gc must be true or false",
+		},
+	}
+	// a repo's file can't make more branches deletable
+	for _, key := range []string{"remote", "accept-risk", "max-delete", "delete-upstream-gone", "delete-duplicates", "clean-backups", "expire-reflogs", "gc", "gc-prune", "min-subject-score", "min-diff-score", "i-am-maintainer", "ignore-paths", "base"} {
+		tests = append(tests, configFileTest{name: "repo " + key, contents: key + ": 0\n", err: key + " can only be set on the command line or in "})
+	}
+	p := newTestParser(t, &opts{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, err := configFileArgs(p, writeConfigFile(t, test.contents), test.global)
+			if test.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+					t.Fatalf("configFileArgs() = %q, %v, expected %s", args, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(args, test.args) {
+				t.Errorf("configFileArgs() = %q, expected %q", args, test.args)
+			}
+		})
+	}
+}
+
+func TestNegateConfigFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		configArgs []string
+		args       []string
+		expected   []string // what is parsed
+	}{
+		{
+			name:       "negated",
+			configArgs: []string{"--strip-emoji", "--sort=age"},
+			args:       []string{"--no-strip-emoji", "report"},
+			expected:   []string{"--sort=age", "report"},
+		},
+		{
+			name:       "not set",
+			configArgs: []string{"--sort=age"},
+			args:       []string{"--no-gc"},
+			expected:   []string{"--sort=age"},
+		},
+		{
+			name:     "an option's own name",
+			args:     []string{"--no-truncate"},
+			expected: []string{"--no-truncate"},
+		},
+		{
+			name:       "not a flag",
+			configArgs: []string{"--sort=age"},
+			args:       []string{"--no-sort", "--no-such-option"},
+			expected:   []string{"--sort=age", "--no-sort", "--no-such-option"},
+		},
+		{
+			name:       "after --",
+			configArgs: []string{"--gc"},
+			args:       []string{"restore", "--", "--no-gc"},
+			expected:   []string{"--gc", "restore", "--", "--no-gc"},
+		},
+	}
+	p := newTestParser(t, &opts{})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configArgs, args := negateConfigFlags(p, test.configArgs, test.args)
+			if got := append(append([]string{}, configArgs...), args...); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("negateConfigFlags() = %q, expected %q", got, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// deletionLogRef is updated as each branch is deleted, so its reflog records
+// what was deleted, when, and why (see git log -g refs/branch-cleanup/log) long
+// after the branch's own reflog is gone. Each entry is a commit of the empty
+// tree, with no parents, recording the deleted tip in its deletedTipTrailer:
+// a new commit each time, since git doesn't log updates which leave a ref
+// unchanged (e.g. when two deleted branches point at the same commit), which
+// doesn't keep the deleted commits reachable, so git gc still frees them.
+var deletionLogRef = refNamespace + "refs/branch-cleanup/log"
+
+const deletedTipTrailer = "Deleted-Tip"
+
+// isReflogDisabled returns true when core.logAllRefUpdates turns reflogs off
+func isReflogDisabled() (bool, error) {
+	value, err := getGitConfig("core.logAllRefUpdates")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(value) {
+	case "false", "no", "off", "0":
+		return true, nil
+	}
+	return false, nil
+}
+
+// logDeletion records that branch was deleted at tip, and why, in the reflog
+// of deletionLogRef
+func logDeletion(branch, tip, reason string) error {
+	disabled, err := isReflogDisabled()
+	if err != nil || disabled {
+		return err
+	}
+	message := fmt.Sprintf("branch-cleanup: delete %s: %s", branch, reason)
+	emptyTree, err := runCommandTrimmedOutput("git", "hash-object", "-w", "-t", "tree", os.DevNull)
+	if err != nil {
+		return err
+	}
+	entry, err := runCommandTrimmedOutput("git", "commit-tree", "-m", message, "-m", deletedTipTrailer+": "+tip, emptyTree)
+	if err != nil {
+		return err
+	}
+	return runCommand("git", "update-ref", "--create-reflog", "-m", message, deletionLogRef, entry)
+}
+
+// describeDeletion explains why a reported branch is deleted, for the deletion log
+func describeDeletion(b *BranchReport) string {
+	reason := b.Classification
+	if b.PotentialMerge != nil && b.MatchedSha != "" {
+		reason += fmt.Sprintf(" as %s (subject score %.2f, diff score %.2f)", b.MatchedSha, b.SubjectScore, b.DiffScore)
+	} else if b.PotentialMerge != nil && b.Merged {
+		reason += " at " + b.MergedSha
+	}
+	if b.Reason != "" {
+		reason += ": " + b.Reason
+	}
+	return reason
+}
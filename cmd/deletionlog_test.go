@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDeletionLogDoesNotPin checks that the deletion log records the deleted
+// tip without keeping it reachable, so git gc still frees the deleted branch
+func TestDeletionLogDoesNotPin(t *testing.T) {
+	r := newTestRepo(t)
+	r.git("checkout", "--quiet", "-b", "done")
+	r.commit("Done", "f1.txt", 7, "done")
+	tip := r.git("rev-parse", "HEAD")
+	r.git("checkout", "--quiet", "main")
+	r.enter()
+
+	if err := logDeletion("done", tip, "merged at "+tip); err != nil {
+		t.Fatal(err)
+	}
+	r.git("branch", "--quiet", "-D", "done")
+	tips, err := getDeletedTips()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tips) != 1 || tips[0].Branch != "done" || tips[0].Tip != tip || tips[0].Reason != "merged at "+tip {
+		t.Fatalf("getDeletedTips() = %+v, expected done at %s", tips, tip)
+	}
+	if reachable := r.git("rev-list", deletionLogRef); strings.Contains(reachable, tip) {
+		t.Fatalf("%s reaches the deleted tip %s", deletionLogRef, tip)
+	}
+
+	r.git("reflog", "expire", "--expire=now", "HEAD")
+	r.git("gc", "--quiet", "--prune=now")
+	if _, err := getGitRevParse(tip + "^{commit}"); err == nil {
+		t.Fatalf("git gc kept the deleted tip %s", tip)
+	}
+	if tips, err := getDeletedTips(); err != nil || len(tips) != 0 {
+		t.Fatalf("getDeletedTips() = %+v, %v after the tip was pruned, expected none", tips, err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// keepOrder decides which of a set of duplicate branches is kept: the one with
+// the shortest name, since copies tend to be named after the original (foo-backup)
+func keepOrder(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// markDuplicates reclassifies unmerged branches which point at the same commit,
+// or contain the same diff relative to the base, as another unmerged branch.
+// Merged branches are left alone, since they are cleaned up regardless.
+func markDuplicates(report *Report, baseRev string) error {
+	tips, err := getBranchTips()
+	if err != nil {
+		return err
+	}
+
+	byTip := map[string][]*BranchReport{}
+	for _, b := range report.Branches {
+		switch b.Classification {
+		case classUnmerged, classPotential, classUnknown:
+			byTip[tips[b.Branch]] = append(byTip[tips[b.Branch]], b)
+		}
+	}
+
+	// branches with different tips may still have the same changes, e.g. a copy rebased onto a newer base
+	byDiff := map[[sha256.Size]byte][]*BranchReport{}
+	for _, group := range byTip {
+		b := group[0]
+		mergeBase, err := getGitMergeBase(baseRev, branchRef(b.Branch))
+		if err != nil {
+			continue // unrelated history
+		}
+		diff, err := getGitDiff(mergeBase, branchRef(b.Branch))
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			continue
+		}
+		key := sha256.Sum256([]byte(removeGitShaFromGitDiff(diff)))
+		byDiff[key] = append(byDiff[key], group...)
+	}
+
+	for _, group := range byDiff {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return keepOrder(group[i].Branch, group[j].Branch) })
+		keep := group[0]
+		for _, b := range group[1:] {
+			b.Classification = classDuplicate
+			if tips[b.Branch] == tips[keep.Branch] {
+				b.Reason = fmt.Sprintf(T("same commit as %s"), keep.Branch)
+			} else {
+				b.Reason = fmt.Sprintf(T("same diff as %s"), keep.Branch)
+			}
+		}
+	}
+	return nil
+}
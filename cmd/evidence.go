@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// exportEvidence writes, for each candidate, the branch's diff, the matched
+// base commit's diff, and the analysis metadata under dir/<branch>/, so the
+// match can be reviewed offline without re-running git
+func exportEvidence(dir string, reports []*BranchReport) error {
+	for _, b := range reports {
+		if !isCandidate(b.Classification) || b.PotentialMerge == nil {
+			continue
+		}
+		branchDir := filepath.Join(dir, url.PathEscape(b.Branch))
+		if err := os.MkdirAll(branchDir, 0755); err != nil {
+			return err
+		}
+
+		branchDiff, err := getGitDiff(b.MergeBase, b.TipSha)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(branchDir, "branch.diff"), []byte(branchDiff+"\n"), 0644); err != nil {
+			return err
+		}
+
+		if b.MatchedSha != "" {
+			matchedDiff, err := getGitDiff(b.MatchedSha+"^", b.MatchedSha)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(branchDir, "matched.diff"), []byte(matchedDiff+"\n"), 0644); err != nil {
+				return err
+			}
+		}
+
+		metadata, err := json.MarshalIndent(b, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(branchDir, "metadata.json"), append(metadata, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePatches writes the branch's diff and the diff of the base commit it
+// matched under dir, returning their names, which say which side is which
+func writePatches(dir string, b *BranchReport) (string, string, error) {
+	branchDiff, err := getGitDiff(b.MergeBase, b.TipSha)
+	if err != nil {
+		return "", "", err
+	}
+	matchedDiff, err := getGitDiff(b.MatchedSha+"^", b.MatchedSha)
+	if err != nil {
+		return "", "", err
+	}
+	branchFile := url.PathEscape(b.Branch) + ".diff"
+	matchedFile := b.MatchedSha[:12] + ".diff"
+	if err := os.WriteFile(filepath.Join(dir, branchFile), []byte(withoutIndexLines(branchDiff)), 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, matchedFile), []byte(withoutIndexLines(matchedDiff)), 0644); err != nil {
+		return "", "", err
+	}
+	return branchFile, matchedFile, nil
+}
+
+// wordDiffOption colors word diffs written to a terminal, and otherwise marks
+// changes as [-removed-]{+added+}
+func wordDiffOption(out *os.File) string {
+	if getTerminalWidth(out.Fd()) > 0 {
+		return "--word-diff=color"
+	}
+	return "--word-diff=plain"
+}
+
+// maxNearMissLines is how much of a near-miss's word diff the report shows
+const maxNearMissLines = 20
+
+// getNearMissDiff returns the hunks where the branch's patch and its match's
+// differ, word by word, for the report to show below a potential match. The
+// difference is usually a single conflict resolution from a rebase, which
+// decides at a glance whether deleting the branch is safe.
+func getNearMissDiff(b *BranchReport) ([]string, error) {
+	dir, err := os.MkdirTemp("", "git-branch-cleanup-evidence-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	branchFile, matchedFile, err := writePatches(dir, b)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("git", "--no-pager", "diff", "--no-index", "--unified=1", wordDiffOption(os.Stdout), "--", branchFile, matchedFile)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil && !isExitStatus(err, 1) {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		// the headers only name the temporary files; each hunk header names the file it is in
+		if len(lines) == 0 && !strings.Contains(line, "@@") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > maxNearMissLines {
+		lines = append(lines[:maxNearMissLines], fmt.Sprintf(T("... (%d more lines; see the show command)"), len(lines)-maxNearMissLines))
+	}
+	return lines, nil
+}
+
+// showEvidence shows how the branch's diff differs from the diff of the base
+// commit it matched, as a word diff of the two patches, so a near-match can be
+// verified without eyeballing both in full. Like git diff, it is colored and
+// paged according to the user's git config when stdout is a terminal.
+func showEvidence(rs *runState, branch string) error {
+	report, err := rs.analyzeOnly(branch)
+	if err != nil {
+		return err
+	}
+	if len(report.Branches) == 0 {
+		if branch == rs.currentBranch {
+			return fmt.Errorf("%s is the current branch", branch)
+		}
+		return fmt.Errorf("%s is not analyzed with the given --scope, --touches, or --owned-by", branch)
+	}
+	return showWordDiff(report.Branches[0], os.Stdout)
+}
+
+// showWordDiff writes the word diff of the branch's patch against the patch of
+// the base commit it matched to out
+func showWordDiff(b *BranchReport, out *os.File) error {
+	if b.PotentialMerge == nil || b.MatchedSha == "" {
+		if b.Reason != "" {
+			return fmt.Errorf("%s is %s (%s), so no base commit matched it", b.Branch, b.Classification, b.Reason)
+		}
+		return fmt.Errorf("%s is %s, so no base commit matched it", b.Branch, b.Classification)
+	}
+
+	dir, err := os.MkdirTemp("", "git-branch-cleanup-evidence-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	branchFile, matchedFile, err := writePatches(dir, b)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, T("%s (subject score %.2f, diff score %.2f) compared with %s:\n"), b.Branch, b.SubjectScore, b.DiffScore, b.MatchedSha)
+	cmd := exec.Command("git", "diff", "--no-index", wordDiffOption(out), "--", branchFile, matchedFile)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil && !isExitStatus(err, 1) {
+		return err // git diff exits 1 when there are differences
+	}
+	return nil
+}
+
+// withoutIndexLines drops the index lines of a patch's file headers, whose
+// blob ids differ between any two patches and only distract from the changes
+func withoutIndexLines(patch string) string {
+	lines := []string{}
+	for _, line := range strings.Split(patch, "\n") {
+		if !strings.HasPrefix(line, "index ") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const expiryFileName = "expiry.json"
+
+// ScheduledDeletion is a branch whose deletion from the remote was announced
+// on its pull request (see --expiry-label), and which is deleted once it is due
+type ScheduledDeletion struct {
+	Branch      string    `json:"branch"`
+	TipSha      string    `json:"tipSha"`
+	PullRequest int       `json:"pullRequest,omitempty"` // the labelled pull request, or 0 when none is known
+	ScheduledAt time.Time `json:"scheduledAt"`
+	DueAt       time.Time `json:"dueAt"`
+}
+
+// Forge labels pull requests (or merge requests) on the forge hosting the base's remote
+type Forge interface {
+	addLabel(number int, label string) error
+	hasLabel(number int, label string) (bool, error)
+}
+
+// GitHub talks to the REST API of a GitHub repository, with the token in
+// GITHUB_TOKEN (as in a workflow). GITHUB_API_URL is set for GitHub Enterprise.
+type GitHub struct {
+	apiURL string
+	repo   string // owner/name
+	token  string
+	client *http.Client
+}
+
+func newGitHub(webURL string) (*GitHub, error) {
+	g := &GitHub{
+		apiURL: os.Getenv("GITHUB_API_URL"),
+		repo:   os.Getenv("GITHUB_REPOSITORY"),
+		token:  os.Getenv("GITHUB_TOKEN"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if g.apiURL == "" {
+		g.apiURL = "https://api.github.com"
+	}
+	if g.repo == "" {
+		_, path, _ := strings.Cut(strings.TrimPrefix(webURL, "https://"), "/")
+		g.repo = path
+	}
+	if g.token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN must be set to a token which may label pull requests")
+	}
+	return g, nil
+}
+
+func (g *GitHub) do(method, path string, body interface{}, out interface{}) error {
+	headers := map[string]string{"Authorization": "Bearer " + g.token, "Accept": "application/vnd.github+json"}
+	return doJSONRequest(g.client, method, strings.TrimSuffix(g.apiURL, "/")+"/repos/"+g.repo+path, headers, body, out)
+}
+
+func (g *GitHub) addLabel(number int, label string) error {
+	return g.do("POST", fmt.Sprintf("/issues/%d/labels", number), map[string][]string{"labels": {label}}, nil)
+}
+
+func (g *GitHub) hasLabel(number int, label string) (bool, error) {
+	var labels []struct {
+		Name string `json:"name"`
+	}
+	if err := g.do("GET", fmt.Sprintf("/issues/%d/labels", number), nil, &labels); err != nil {
+		return false, err
+	}
+	for _, l := range labels {
+		if l.Name == label {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getForge returns the forge hosting the base's remote, for the forges whose
+// API is known
+func (rs *runState) getForge() (Forge, error) {
+	remote, err := rs.getBaseRemote()
+	if err != nil {
+		return nil, err
+	}
+	webURL, err := getRemoteWebURL(remote)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.Contains(webURL, "gitlab"):
+		return newGitLab()
+	case strings.Contains(webURL, "github"):
+		return newGitHub(webURL)
+	}
+	return nil, fmt.Errorf("pull requests can only be labelled on GitHub and GitLab, not %s", remote)
+}
+
+// Expiry gives authors a window to object to the deletion of their branches
+// from the remote: a deletion is first announced by labelling the branch's
+// pull request, and only carried out once expiryDays have passed with the
+// label still on it. Removing the label keeps the branch.
+type Expiry struct {
+	forge      Forge
+	label      string
+	days       int
+	dryRun     bool
+	scheduled  map[string]*ScheduledDeletion
+	objections []*Rejection
+}
+
+func (rs *runState) loadExpiry() (*Expiry, error) {
+	forge, err := rs.getForge()
+	if err != nil {
+		return nil, err
+	}
+	path, err := getStateFilePath(expiryFileName)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*ScheduledDeletion
+	if err := readJSONFile(path, &entries); err != nil {
+		return nil, err
+	}
+	e := &Expiry{
+		forge:     forge,
+		label:     rs.progOpts.ExpiryLabel,
+		days:      rs.progOpts.ExpiryDays,
+		dryRun:    rs.deleteDisabled != nil,
+		scheduled: map[string]*ScheduledDeletion{},
+	}
+	for _, s := range entries {
+		e.scheduled[s.Branch] = s
+	}
+	return e, nil
+}
+
+// getPullRequestNumber returns the pull request the branch was merged from, or 0
+func getPullRequestNumber(b *BranchReport) (int, error) {
+	if b.PullRequest != nil {
+		return b.PullRequest.Number, nil
+	}
+	if b.PotentialMerge == nil || b.MergedSha == "" {
+		return 0, nil
+	}
+	pr, err := getPullRequest(b.MergedSha)
+	if err != nil || pr == nil {
+		return 0, err
+	}
+	return pr.Number, nil
+}
+
+// isDue returns true when the branch's deletion was announced long enough ago,
+// and nobody objected; otherwise its deletion is announced now, unless it
+// already was, or the branch is kept for good when the label was removed
+func (e *Expiry) isDue(b *BranchReport) (bool, error) {
+	tip, err := b.tipSha()
+	if err != nil {
+		return false, err
+	}
+	s := e.scheduled[b.Branch]
+	if s != nil && s.TipSha != tip {
+		s = nil // pushed to since, so the window starts over
+	}
+	if s == nil {
+		number, err := getPullRequestNumber(b)
+		if err != nil {
+			return false, err
+		}
+		now := time.Now()
+		s = &ScheduledDeletion{Branch: b.Branch, TipSha: tip, PullRequest: number, ScheduledAt: now, DueAt: now.AddDate(0, 0, e.days)}
+		if e.dryRun {
+			reportf(T("would schedule %s for deletion on %s\n"), b.Branch, s.DueAt.Format("2006-01-02"))
+			return false, nil
+		}
+		if number != 0 {
+			if err := e.forge.addLabel(number, e.label); err != nil {
+				return false, fmt.Errorf("failed to label pull request #%d: %w", number, err)
+			}
+			reportf(T("%s is scheduled for deletion on %s; pull request #%d is labelled %s until then\n"), b.Branch, s.DueAt.Format("2006-01-02"), number, e.label)
+		} else {
+			reportf(T("%s is scheduled for deletion on %s\n"), b.Branch, s.DueAt.Format("2006-01-02"))
+		}
+		e.scheduled[b.Branch] = s
+		return false, nil
+	}
+	if time.Now().Before(s.DueAt) {
+		reportf(T("%s is scheduled for deletion on %s\n"), b.Branch, s.DueAt.Format("2006-01-02"))
+		return false, nil
+	}
+	if s.PullRequest != 0 {
+		labelled, err := e.forge.hasLabel(s.PullRequest, e.label)
+		if err != nil {
+			return false, err
+		}
+		if !labelled {
+			reportf(T("keeping %s, since the %s label was removed from pull request #%d\n"), b.Branch, e.label, s.PullRequest)
+			if !e.dryRun {
+				e.objections = append(e.objections, &Rejection{Branch: b.Branch, TipSha: tip, RejectedAt: time.Now()})
+				delete(e.scheduled, b.Branch)
+			}
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// save writes the deletions which are still scheduled, forgetting those of
+// branches which were deleted or no longer exist
+func (e *Expiry) save(report *Report) error {
+	if e.dryRun {
+		return nil
+	}
+	entries := []*ScheduledDeletion{}
+	for _, b := range report.Branches {
+		if s := e.scheduled[b.Branch]; s != nil && !b.Deleted {
+			entries = append(entries, s)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Branch < entries[j].Branch })
+	path, err := getStateFilePath(expiryFileName)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(path, entries)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestExpiryWithoutPotentialMerge schedules a branch whose report has no
+// potential merge, as for upstream-gone, backup and duplicate branches
+func TestExpiryWithoutPotentialMerge(t *testing.T) {
+	r := newTestRepo(t)
+	r.git("checkout", "--quiet", "-b", "gone")
+	r.commit("Landed elsewhere", "f2.txt", 5, "gone")
+	tip := r.git("rev-parse", "HEAD")
+	r.git("checkout", "--quiet", "main")
+	r.enter()
+
+	e := &Expiry{label: "stale-branch", days: 7, scheduled: map[string]*ScheduledDeletion{}}
+	b := &BranchReport{Branch: "gone", Classification: classUpstreamGone}
+	if due, err := e.isDue(b); err != nil || due {
+		t.Fatalf("isDue() = %v, %v on first sight, expected false, nil", due, err)
+	}
+	s := e.scheduled["gone"]
+	if s == nil || s.TipSha != tip {
+		t.Fatalf("scheduled %+v, expected the tip %s", s, tip)
+	}
+	s.DueAt = time.Now().Add(-time.Hour)
+	if due, err := e.isDue(b); err != nil || !due {
+		t.Fatalf("isDue() = %v, %v once due, expected true, nil", due, err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PullRequest is the pull (or merge) request a base commit was merged from
+type PullRequest struct {
+	Number   int       `json:"number"`
+	Title    string    `json:"title"`
+	URL      string    `json:"url,omitempty"` // only known when the base's remote is hosted on a forge
+	MergedAt time.Time `json:"mergedAt"`
+}
+
+var (
+	// GitHub squash merges: "Add the widget (#123)"
+	squashSubjectRegexp = regexp.MustCompile(`^(.*) \(#([0-9]+)\)$`)
+	// GitHub merge commits: "Merge pull request #123 from owner/branch", titled in the body
+	mergeSubjectRegexp = regexp.MustCompile(`^Merge pull request #([0-9]+) from \S+$`)
+	// GitLab merge commits: "See merge request group/project!123" in the body
+	mergeRequestRegexp = regexp.MustCompile(`(?m)^See merge request \S+!([0-9]+)$`)
+)
+
+// parsePullRequest finds which pull request a commit was merged from, using
+// the messages forges write when merging; it returns nil when there is none
+func parsePullRequest(subject, body string) *PullRequest {
+	body = strings.TrimSpace(body)
+	if m := squashSubjectRegexp.FindStringSubmatch(subject); m != nil {
+		number, _ := strconv.Atoi(m[2])
+		return &PullRequest{Number: number, Title: m[1]}
+	}
+	if m := mergeSubjectRegexp.FindStringSubmatch(subject); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		return &PullRequest{Number: number, Title: strings.SplitN(body, "\n", 2)[0]}
+	}
+	if m := mergeRequestRegexp.FindStringSubmatch(body); m != nil {
+		number, _ := strconv.Atoi(m[1])
+		return &PullRequest{Number: number, Title: strings.TrimPrefix(subject, "Merge branch ")}
+	}
+	return nil
+}
+
+// getPullRequest returns the pull request commit was merged from, or nil
+func getPullRequest(commit string) (*PullRequest, error) {
+	out, err := runCommandTrimmedOutput("git", "--no-pager", "show", "-s", "--format=%cI%x00%s%x00%b", commit, "--")
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(out, "\x00", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unexpected git show output for %s", commit)
+	}
+	pr := parsePullRequest(parts[1], parts[2])
+	if pr == nil {
+		return nil, nil
+	}
+	pr.MergedAt, err = time.Parse(time.RFC3339, parts[0])
+	return pr, err
+}
+
+var scpLikeURLRegexp = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// getRemoteWebURL returns the web page of a remote's repository, e.g.
+// https://github.com/owner/repo for git@github.com:owner/repo.git, or "" when
+// the remote is a local path
+func getRemoteWebURL(remote string) (string, error) {
+	url, err := getGitConfig("remote." + remote + ".url")
+	if err != nil || url == "" {
+		return "", err
+	}
+	var host, path string
+	if scheme, rest, ok := strings.Cut(url, "://"); ok {
+		if scheme == "file" {
+			return "", nil
+		}
+		host, path, _ = strings.Cut(rest, "/")
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+		host, _, _ = strings.Cut(host, ":") // drop any port, which the web server doesn't share
+	} else if m := scpLikeURLRegexp.FindStringSubmatch(url); m != nil {
+		host, path = m[1], m[2]
+	} else {
+		return "", nil
+	}
+	return "https://" + host + "/" + strings.TrimSuffix(strings.Trim(path, "/"), ".git"), nil
+}
+
+// pullRequestURL returns where a pull request is shown, for the forges whose
+// layout is known
+func pullRequestURL(webURL string, number int) string {
+	switch {
+	case strings.Contains(webURL, "gitlab"):
+		return fmt.Sprintf("%s/-/merge_requests/%d", webURL, number)
+	case strings.Contains(webURL, "github"):
+		return fmt.Sprintf("%s/pull/%d", webURL, number)
+	}
+	return ""
+}
+
+// getBaseRemote returns the remote the base is fetched from
+func (rs *runState) getBaseRemote() (string, error) {
+	if rs.remotes != nil && strings.HasPrefix(rs.baseName, rs.remotes.Upstream+"/") {
+		return rs.remotes.Upstream, nil
+	}
+	remote, err := getGitConfig("branch." + rs.baseName + ".remote")
+	if err != nil || remote != "" {
+		return remote, err
+	}
+	return "origin", nil
+}
+
+// addPullRequests annotates each candidate matched with a base commit with the
+// pull request that commit was merged from
+func (rs *runState) addPullRequests(report *Report) error {
+	remote, err := rs.getBaseRemote()
+	if err != nil {
+		return err
+	}
+	webURL, err := getRemoteWebURL(remote)
+	if err != nil {
+		return err
+	}
+	for _, b := range report.Branches {
+		if !isCandidate(b.Classification) || b.PotentialMerge == nil || b.MatchedSha == "" {
+			continue
+		}
+		b.PullRequest, err = getPullRequest(b.MatchedSha)
+		if err != nil {
+			return err
+		}
+		if b.PullRequest != nil && webURL != "" {
+			b.PullRequest.URL = pullRequestURL(webURL, b.PullRequest.Number)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isUpstreamed returns true when every commit on the branch has an equivalent
+// patch in the base, which is how a fork's branches look once their PR is
+// rebase merged upstream
+func isUpstreamed(baseRev, branch string) (bool, error) {
+	cherry, err := getCherry(baseRev, branchRef(branch))
+	if err != nil {
+		return false, err
+	}
+	for _, line := range cherry {
+		if !strings.HasPrefix(line, "-") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// forkSync deletes the branches which were merged into the upstream remote's
+// default branch, both locally and on the fork, like a run with --remote set to
+// the fork; besides the usual classifications, unmerged branches whose commits
+// all have an equivalent upstream are deleted as upstreamed. With syncBase it
+// also fast-forwards the base branch, locally and on the fork, to the upstream's.
+func forkSync(rs *runState, syncBase bool) error {
+	if rs.remotes == nil {
+		return fmt.Errorf("no upstream remote; fork-sync needs an upstream remote besides the fork (see --upstream-remote)")
+	}
+	if rs.baseName == rs.currentBranch {
+		return fmt.Errorf("the default branch of %s has not been fetched; run git fetch %s", rs.remotes.Upstream, rs.remotes.Upstream)
+	}
+
+	report := rs.analyze()
+	for _, b := range report.Branches {
+		if b.Classification != classUnmerged && b.Classification != classUpstreamGone {
+			continue
+		}
+		upstreamed, err := isUpstreamed(rs.baseRev, b.Branch)
+		if err != nil {
+			return err
+		}
+		if upstreamed {
+			b.Classification = classUpstreamed
+			b.Reason = fmt.Sprintf(T("every commit has an equivalent in %s"), rs.baseName)
+		}
+	}
+	rs.progOpts.Remote = rs.remotes.Fork
+	rs.cleanup(report)
+
+	if !syncBase {
+		return nil
+	}
+	if rs.deleteDisabled != nil {
+		reportf(T("would fast-forward %s to %s\n"), rs.currentBranch, rs.baseName)
+		return nil
+	}
+	infof(T("fast-forwarding %s to %s\n"), rs.currentBranch, rs.baseName)
+	if err := runCommand("git", "merge", "--ff-only", "--quiet", rs.baseRev); err != nil {
+		return fmt.Errorf("failed to fast-forward %s: %w", rs.currentBranch, err)
+	}
+	if err := checkAllowed(opRemoteDelete); err != nil {
+		warnf(T("not fast-forwarding %s on %s: %v\n"), rs.currentBranch, rs.remotes.Fork, err)
+		return nil
+	}
+	if err := runCommand("git", "push", "--quiet", rs.remotes.Fork, rs.baseRev+":"+branchRef(rs.currentBranch)); err != nil {
+		return fmt.Errorf("failed to fast-forward %s on %s: %w", rs.currentBranch, rs.remotes.Fork, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"text/template"
+)
+
+// reportTemplate is set by --format, in which case each branch is reported by
+// executing it (like git log --pretty) instead of with the human-readable report lines
+var reportTemplate *template.Template
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// parseReportTemplate parses a --format template, and tries it on an empty
+// branch so that unknown fields are reported before any branch is deleted
+func parseReportTemplate(format string) (*template.Template, error) {
+	t, err := template.New("format").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(io.Discard, &BranchReport{PotentialMerge: &PotentialMerge{}}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// writeFormatted reports each branch with reportTemplate, one per line
+func writeFormatted(w io.Writer, branches []*BranchReport) error {
+	for _, b := range branches {
+		// every field is available, even for branches which were never compared (e.g. skipped ones)
+		data := *b
+		if data.PotentialMerge == nil {
+			data.PotentialMerge = &PotentialMerge{}
+		}
+		var line strings.Builder
+		if err := reportTemplate.Execute(&line, &data); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, strings.TrimSuffix(line.String(), "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GCSummary is the repository's object storage before and after --gc
+type GCSummary struct {
+	Before int64 `json:"before"`
+	After  int64 `json:"after"`
+}
+
+// getRepositorySize returns the bytes used by loose and packed objects
+func getRepositorySize() (int64, error) {
+	lines, err := runCommandSplitLines("git", "count-objects", "-v")
+	if err != nil {
+		return 0, err
+	}
+	var kib int64
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || (key != "size" && key != "size-pack" && key != "size-garbage") {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		kib += n
+	}
+	return kib * 1024, nil
+}
+
+// runGC runs git gc, pruning unreachable objects older than prune
+func runGC(prune string) (*GCSummary, error) {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return nil, err
+	}
+	before, err := getRepositorySize()
+	if err != nil {
+		return nil, err
+	}
+	if err := runCommand("git", "gc", "--quiet", "--prune="+prune); err != nil {
+		return nil, err
+	}
+	after, err := getRepositorySize()
+	if err != nil {
+		return nil, err
+	}
+	return &GCSummary{Before: before, After: after}, nil
+}
+
+// getWorktrees returns the path of every worktree of the repository
+func getWorktrees() ([]string, error) {
+	lines, err := runCommandSplitLines("git", "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	worktrees := []string{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "worktree ") {
+			worktrees = append(worktrees, strings.TrimPrefix(line, "worktree "))
+		}
+	}
+	return worktrees, nil
+}
+
+// expireDeletedFromReflogs deletes the HEAD reflog entries of every worktree
+// which are older than expire, and point at commits only the deleted tips
+// reached, so gc can free them; other entries, e.g. of commits reset away,
+// are kept. It returns how many entries were deleted.
+func expireDeletedFromReflogs(tips []string, expire string) (int, error) {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return 0, err
+	}
+	if len(tips) == 0 {
+		return 0, nil
+	}
+	// e.g. --max-age=1700000000
+	since, err := runCommandTrimmedOutput("git", "rev-parse", "--since="+expire)
+	if err != nil {
+		return 0, err
+	}
+	cutoff, err := strconv.ParseInt(strings.TrimPrefix(since, "--max-age="), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiry date %s", expire)
+	}
+	commits, err := runCommandSplitLines(append(append([]string{"git", "rev-list"}, tips...), "--not", "--all")...)
+	if err != nil {
+		return 0, err
+	}
+	deleted := map[string]bool{}
+	for _, commit := range commits {
+		if commit = strings.TrimSpace(commit); commit != "" {
+			deleted[commit] = true
+		}
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+
+	worktrees, err := getWorktrees()
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for _, worktree := range worktrees {
+		// each entry is <sha> HEAD@{<unix time>}, most recent first, so the n-th is HEAD@{n}
+		lines, err := runCommandSplitLines("git", "-C", worktree, "log", "--walk-reflogs", "--date=unix", "--format=%H %gd", "HEAD", "--")
+		if err != nil {
+			continue // HEAD has no reflog
+		}
+		// deleting an entry renumbers the later ones, so they are deleted last first
+		for n := len(lines) - 1; n >= 0; n-- {
+			sha, selector, ok := strings.Cut(strings.TrimSpace(lines[n]), " ")
+			if !ok || !deleted[sha] {
+				continue
+			}
+			at, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(selector, "HEAD@{"), "}"), 10, 64)
+			if err != nil || at > cutoff {
+				continue
+			}
+			if err := runCommand("git", "-C", worktree, "reflog", "delete", fmt.Sprintf("HEAD@{%d}", n)); err != nil {
+				return expired, err
+			}
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExpireDeletedFromReflogs checks that only the reflog entries of deleted
+// branches are expired, in every worktree
+func TestExpireDeletedFromReflogs(t *testing.T) {
+	r := newTestRepo(t)
+	r.git("checkout", "--quiet", "-b", "done")
+	r.commit("Done", "f1.txt", 7, "done")
+	done := r.git("rev-parse", "HEAD")
+	r.git("checkout", "--quiet", "main")
+	// an unrelated recovery point
+	r.commit("Reset away", "f2.txt", 7, "reset away")
+	resetAway := r.git("rev-parse", "HEAD")
+	r.git("reset", "--quiet", "--hard", "HEAD~1")
+
+	worktree := filepath.Join(filepath.Dir(r.dir), "wt")
+	r.git("worktree", "add", "--quiet", "-b", "elsewhere", worktree)
+	r.gitIn(worktree, "commit", "--quiet", "--allow-empty", "-m", "Elsewhere")
+	elsewhere := r.gitIn(worktree, "rev-parse", "HEAD")
+	r.gitIn(worktree, "checkout", "--quiet", "--detach", "main")
+	r.git("branch", "--quiet", "-D", "done", "elsewhere")
+	r.enter()
+
+	if expired, err := expireDeletedFromReflogs([]string{done, elsewhere}, "1.week.ago"); err != nil || expired != 0 {
+		t.Errorf("expireDeletedFromReflogs() = %d, %v, expected nothing older than a week", expired, err)
+	}
+
+	expired, err := expireDeletedFromReflogs([]string{done, elsewhere}, "now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired == 0 {
+		t.Errorf("no entries were expired")
+	}
+	reflog := r.git("log", "--walk-reflogs", "--format=%H", "HEAD", "--")
+	if strings.Contains(reflog, done) {
+		t.Errorf("HEAD's reflog still has the deleted %s", done)
+	}
+	if !strings.Contains(reflog, resetAway) {
+		t.Errorf("HEAD's reflog lost the unrelated %s", resetAway)
+	}
+	if strings.Contains(r.gitIn(worktree, "log", "--walk-reflogs", "--format=%H", "HEAD", "--"), elsewhere) {
+		t.Errorf("the other worktree's HEAD reflog still has the deleted %s", elsewhere)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// gitLabReviewLabel marks the issues gitlab-review opens, so the next run finds them
+const gitLabReviewLabel = "branch-cleanup"
+
+// gitLabReviewMarker starts the description of the issues gitlab-review opens,
+// telling them apart from other issues with the label
+const gitLabReviewMarker = "<!-- git-branch-cleanup review -->"
+
+// GitLab talks to the REST API of a GitLab project. In a pipeline, the API's URL
+// and the project are known from CI_API_V4_URL and CI_PROJECT_ID; the token,
+// which needs the api scope, is read from GITLAB_TOKEN (a job's own token can't
+// open issues or delete branches).
+type GitLab struct {
+	apiURL  string
+	project string
+	token   string
+	client  *http.Client
+}
+
+func newGitLab() (*GitLab, error) {
+	g := &GitLab{
+		apiURL:  os.Getenv("CI_API_V4_URL"),
+		project: os.Getenv("CI_PROJECT_ID"),
+		token:   os.Getenv("GITLAB_TOKEN"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+	if g.apiURL == "" || g.project == "" {
+		return nil, fmt.Errorf("CI_API_V4_URL and CI_PROJECT_ID must be set, as they are in a GitLab pipeline")
+	}
+	if g.token == "" {
+		return nil, fmt.Errorf("GITLAB_TOKEN must be set to a token with the api scope")
+	}
+	return g, nil
+}
+
+// do sends a request to the project's endpoint path, decoding the response into out
+func (g *GitLab) do(method, path string, body interface{}, out interface{}) error {
+	return g.doAPI(method, "/projects/"+url.PathEscape(g.project)+path, body, out)
+}
+
+// doAPI is like do, for endpoints outside of the project
+func (g *GitLab) doAPI(method, path string, body interface{}, out interface{}) error {
+	endpoint := strings.TrimSuffix(g.apiURL, "/") + path
+	return doJSONRequest(g.client, method, endpoint, map[string]string{"PRIVATE-TOKEN": g.token}, body, out)
+}
+
+// doJSONRequest sends body as JSON, and decodes the JSON response into out
+// unless it is nil; failed requests return the start of the response
+func doJSONRequest(client *http.Client, method, endpoint string, headers map[string]string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		contents, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(contents)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// GitLabIssue is the part of an issue gitlab-review uses
+type GitLabIssue struct {
+	IID         int    `json:"iid"`
+	Description string `json:"description"`
+	WebURL      string `json:"web_url"`
+	Author      struct {
+		ID int `json:"id"`
+	} `json:"author"`
+}
+
+// findOpenReviewIssue returns the open review issue opened with the same
+// token, since only those list what a previous run analyzed; anyone else's
+// issue could tick branches which were never offered
+func (g *GitLab) findOpenReviewIssue() (*GitLabIssue, error) {
+	var user struct {
+		ID int `json:"id"`
+	}
+	if err := g.doAPI("GET", "/user", nil, &user); err != nil {
+		return nil, err
+	}
+	var issues []*GitLabIssue
+	query := fmt.Sprintf("?state=opened&labels=%s&author_id=%d", url.QueryEscape(gitLabReviewLabel), user.ID)
+	if err := g.do("GET", "/issues"+query, nil, &issues); err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		if issue.Author.ID == user.ID && strings.HasPrefix(issue.Description, gitLabReviewMarker) {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+func (g *GitLab) createIssue(title, description string) (*GitLabIssue, error) {
+	var issue GitLabIssue
+	body := map[string]string{"title": title, "description": description, "labels": gitLabReviewLabel}
+	if err := g.do("POST", "/issues", body, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// closeIssue closes the issue, commenting what came of it
+func (g *GitLab) closeIssue(issue *GitLabIssue, comment string) error {
+	if err := g.do("POST", fmt.Sprintf("/issues/%d/notes", issue.IID), map[string]string{"body": comment}, nil); err != nil {
+		return err
+	}
+	return g.do("PUT", fmt.Sprintf("/issues/%d", issue.IID), map[string]string{"state_event": "close"}, nil)
+}
+
+func (g *GitLab) deleteBranch(branch string) error {
+	return g.do("DELETE", "/repository/branches/"+url.PathEscape(branch), nil, nil)
+}
+
+func (g *GitLab) addLabel(number int, label string) error {
+	return g.do("PUT", fmt.Sprintf("/merge_requests/%d", number), map[string]string{"add_labels": label}, nil)
+}
+
+func (g *GitLab) hasLabel(number int, label string) (bool, error) {
+	var mr struct {
+		Labels []string `json:"labels"`
+	}
+	if err := g.do("GET", fmt.Sprintf("/merge_requests/%d", number), nil, &mr); err != nil {
+		return false, err
+	}
+	for _, l := range mr.Labels {
+		if l == label {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// - [x] `feature/foo` matched as 0123abc (...) <!-- tip 0123abcd... -->
+var reviewItemRegexp = regexp.MustCompile("^\\s*- \\[([ xX])\\] `([^`]+)`.*<!-- tip ([0-9a-f]+) -->")
+
+// ReviewItem is a branch listed in a review issue
+type ReviewItem struct {
+	Branch string
+	TipSha string
+	Ticked bool
+}
+
+func parseReviewIssue(description string) []*ReviewItem {
+	items := []*ReviewItem{}
+	for _, line := range strings.Split(description, "\n") {
+		if m := reviewItemRegexp.FindStringSubmatch(line); m != nil {
+			items = append(items, &ReviewItem{Branch: m[2], TipSha: m[3], Ticked: m[1] != " "})
+		}
+	}
+	return items
+}
+
+func formatReviewIssue(rs *runState, candidates []*BranchReport) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(gitLabReviewMarker + "\n")
+	fmt.Fprintf(&sb, "These branches look merged into `%s`. Tick the ones to delete; the next scheduled run deletes them, unless they changed since.\n\n", rs.baseName)
+	for _, b := range candidates {
+		tip, err := b.tipSha()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "- [ ] `%s` %s <!-- tip %s -->\n", b.Branch, markdownEscape(describeDeletion(b)), tip)
+	}
+	return sb.String(), nil
+}
+
+// resolveReviewIssue deletes the branches ticked in the review issue which
+// are still at the reviewed tip, and closes it, unless remoteDisabled says why
+// nothing may be deleted
+func resolveReviewIssue(g *GitLab, issue *GitLabIssue, remote string, remoteDisabled error) error {
+	results := []string{}
+	for _, item := range parseReviewIssue(issue.Description) {
+		if !item.Ticked {
+			continue
+		}
+		tip, err := getGitRevParse(branchRef(item.Branch))
+		switch {
+		case err != nil:
+			results = append(results, fmt.Sprintf("- `%s` no longer exists", item.Branch))
+		case tip != item.TipSha:
+			reportf(T("%s has changed since it was reviewed; keeping it\n"), item.Branch)
+			results = append(results, fmt.Sprintf("- `%s` changed since it was reviewed, so it was kept", item.Branch))
+		case remoteDisabled != nil:
+			reportf(T("would delete branch %s\n"), item.Branch)
+			results = append(results, fmt.Sprintf("- `%s` was not deleted: %v", item.Branch, remoteDisabled))
+		default:
+			infof(T("deleting %s from %s\n"), item.Branch, remote)
+			if err := g.deleteBranch(item.Branch); err != nil {
+				warnf(T("failed to delete branch %s: %v\n"), item.Branch, err)
+				results = append(results, fmt.Sprintf("- `%s` could not be deleted: %v", item.Branch, err))
+				continue
+			}
+			results = append(results, fmt.Sprintf("- `%s` was deleted", item.Branch))
+		}
+	}
+	if remoteDisabled != nil {
+		return nil // left open for a run which may delete
+	}
+	comment := "Nothing was ticked, so nothing was deleted."
+	if len(results) > 0 {
+		comment = strings.Join(results, "\n")
+	}
+	return g.closeIssue(issue, comment)
+}
+
+// gitLabReview is meant for a scheduled pipeline: the branches ticked in the
+// review issue the last run opened are deleted, then an issue listing the
+// remaining candidates is opened, for the next run to act on. When branches
+// may not be deleted from the remote, nothing is written to GitLab either,
+// since the review couldn't be carried out.
+func gitLabReview(rs *runState, remote string) error {
+	g, err := newGitLab()
+	if err != nil {
+		return err
+	}
+	remoteDisabled := checkAllowed(opRemoteDelete)
+	if remoteDisabled == nil && rs.progOpts.DryRun {
+		remoteDisabled = errors.New("--dry-run was given")
+	}
+	if remoteDisabled != nil {
+		infof(T("leaving GitLab unchanged: %v\n"), remoteDisabled)
+	}
+	if err := rs.useRemoteBranches(remote); err != nil {
+		return err
+	}
+	issue, err := g.findOpenReviewIssue()
+	if err != nil {
+		return err
+	}
+	if issue != nil {
+		if err := resolveReviewIssue(g, issue, remote, remoteDisabled); err != nil {
+			return err
+		}
+		if remoteDisabled != nil {
+			return nil // the review stays open until it can be carried out
+		}
+		if err := fetchRemote(remote); err != nil {
+			return fmt.Errorf("failed to fetch %s after deleting branches: %w", remote, err)
+		}
+		if err := rs.useRemoteBranches(remote); err != nil {
+			return err
+		}
+	}
+
+	candidates := getCandidates(rs.analyze())
+	if len(candidates) == 0 {
+		reportf(T("no branches need review\n"))
+		return nil
+	}
+	if remoteDisabled != nil {
+		reportf(T("would open an issue listing %d branches\n"), len(candidates))
+		return nil
+	}
+	description, err := formatReviewIssue(rs, candidates)
+	if err != nil {
+		return err
+	}
+	issue, err = g.createIssue(fmt.Sprintf("%d branches look merged", len(candidates)), description)
+	if err != nil {
+		return err
+	}
+	reportf(T("opened %s listing %d branches\n"), issue.WebURL, len(candidates))
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// TestReviewIssueRoundTrip checks that the review issue records each branch's
+// tip, including branches whose report has no potential merge
+func TestReviewIssueRoundTrip(t *testing.T) {
+	r := newTestRepo(t)
+	r.git("checkout", "--quiet", "-b", "gone")
+	r.commit("Landed elsewhere", "f2.txt", 5, "gone")
+	r.git("branch", "gone-backup")
+	r.git("checkout", "--quiet", "main")
+	tip := r.git("rev-parse", "gone")
+	r.enter()
+
+	candidates := []*BranchReport{
+		{Branch: "gone", Classification: classUpstreamGone, Reason: "upstream origin/gone is gone"},
+		{Branch: "gone-backup", Classification: classBackup, Reason: "contained in gone"},
+	}
+	description, err := formatReviewIssue(&runState{baseName: "main"}, candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := parseReviewIssue(description)
+	if len(items) != len(candidates) {
+		t.Fatalf("parsed %d items, expected %d:\n%s", len(items), len(candidates), description)
+	}
+	for i, item := range items {
+		if item.Branch != candidates[i].Branch || item.TipSha != tip || item.Ticked {
+			t.Errorf("parsed %+v, expected %s at %s, unticked", item, candidates[i].Branch, tip)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// graphColors color codes branches by classification; anything else is gray
+var graphColors = map[string]string{
+	classMerged:            "green",
+	classTrackingDuplicate: "green",
+	classMatched:           "green",
+	classRevert:            "green",
+	classMergedIntoDeleted: "green",
+	classUpstreamed:        "green",
+	classEmpty:             "yellowgreen",
+	classPotential:         "orange",
+	classUpstreamGone:      "orange",
+	classDuplicate:         "gold",
+	classBackup:            "gold",
+	classRebased:           "skyblue",
+	classUnmerged:          "red",
+}
+
+// basePoint is a base commit which branches fork from, or were merged as
+type basePoint struct {
+	sha     string
+	time    int64
+	subject string
+	forks   []*BranchReport
+	merges  []*BranchReport
+}
+
+// getBasePoints returns where each branch forked from the base, and the base
+// commit it was merged as, ordered from oldest to newest
+func getBasePoints(report *Report, baseRev string) ([]*basePoint, error) {
+	points := map[string]*basePoint{}
+	point := func(sha string) *basePoint {
+		if points[sha] == nil {
+			points[sha] = &basePoint{sha: sha}
+		}
+		return points[sha]
+	}
+	for _, b := range report.Branches {
+		mergeBase := ""
+		if b.PotentialMerge != nil {
+			mergeBase = b.MergeBase
+			if b.MatchedSha != "" && b.MatchedSha != mergeBase && isCandidate(b.Classification) {
+				point(b.MatchedSha).merges = append(point(b.MatchedSha).merges, b)
+			}
+		}
+		if mergeBase == "" {
+			var err error
+			if mergeBase, err = getGitMergeBase(baseRev, branchRef(b.Branch)); err != nil {
+				continue // unrelated history
+			}
+		}
+		point(mergeBase).forks = append(point(mergeBase).forks, b)
+	}
+
+	shas := []string{}
+	for sha := range points {
+		shas = append(shas, sha)
+	}
+	if len(shas) > 0 {
+		lines, err := runCommandSplitLines(append([]string{"git", "show", "-s", "--format=%H %ct %s"}, shas...)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			parts := strings.SplitN(line, " ", 3)
+			if len(parts) != 3 || points[parts[0]] == nil {
+				continue
+			}
+			points[parts[0]].time, _ = strconv.ParseInt(parts[1], 10, 64)
+			points[parts[0]].subject = parts[2]
+		}
+	}
+
+	ordered := []*basePoint{}
+	for _, p := range points {
+		ordered = append(ordered, p)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].time != ordered[j].time {
+			return ordered[i].time < ordered[j].time
+		}
+		return ordered[i].sha < ordered[j].sha
+	})
+	return ordered, nil
+}
+
+func branchGraphColor(classification string) string {
+	if color, ok := graphColors[classification]; ok {
+		return color
+	}
+	return "gray"
+}
+
+// writeDOTGraph writes the graph in Graphviz DOT format, e.g. for dot -Tsvg
+func writeDOTGraph(w io.Writer, baseName string, points []*basePoint) {
+	fmt.Fprintf(w, "digraph branches {\n")
+	fmt.Fprintf(w, "  rankdir=LR;\n")
+	fmt.Fprintf(w, "  label=%q;\n", baseName)
+	fmt.Fprintf(w, "  node [shape=box, style=filled, fillcolor=white];\n")
+	for i, p := range points {
+		fmt.Fprintf(w, "  %q [shape=ellipse, label=%q];\n", p.sha, p.sha[:7]+" "+p.subject)
+		if i > 0 {
+			fmt.Fprintf(w, "  %q -> %q [weight=10];\n", points[i-1].sha, p.sha)
+		}
+	}
+	declared := map[string]bool{}
+	for _, p := range points {
+		for _, b := range append(append([]*BranchReport{}, p.forks...), p.merges...) {
+			if !declared[b.Branch] {
+				declared[b.Branch] = true
+				fmt.Fprintf(w, "  %q [fillcolor=%s, label=%q];\n", "branch:"+b.Branch, branchGraphColor(b.Classification), b.Branch+"\n"+b.Classification)
+			}
+		}
+		for _, b := range p.forks {
+			fmt.Fprintf(w, "  %q -> %q;\n", p.sha, "branch:"+b.Branch)
+		}
+		for _, b := range p.merges {
+			fmt.Fprintf(w, "  %q -> %q [style=dashed, label=\"merged as\"];\n", "branch:"+b.Branch, p.sha)
+		}
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// writeASCIIGraph writes the base history as a line of commits, with the
+// branches hanging off the commits they forked from or were merged as
+func writeASCIIGraph(w io.Writer, baseName string, points []*basePoint) {
+	fmt.Fprintf(w, "%s\n", baseName)
+	for i := len(points) - 1; i >= 0; i-- {
+		p := points[i]
+		fmt.Fprintf(w, "* %s %s\n", p.sha[:7], p.subject)
+		for _, b := range p.merges {
+			fmt.Fprintf(w, "|   <- %s merged here (%s)\n", b.Branch, b.Classification)
+		}
+		for _, b := range p.forks {
+			fmt.Fprintf(w, "|\\_ %s (%s)\n", b.Branch, b.Classification)
+		}
+	}
+}
+
+// showGraph writes the graph of branch relationships to w; nothing is deleted
+func showGraph(w io.Writer, rs *runState, format string) error {
+	report := rs.analyze()
+	points, err := getBasePoints(report, rs.baseRev)
+	if err != nil {
+		return err
+	}
+	if format == "ascii" {
+		writeASCIIGraph(w, rs.baseName, points)
+	} else {
+		writeDOTGraph(w, rs.baseName, points)
+	}
+	return nil
+}
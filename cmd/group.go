@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GroupSummary holds the per-group totals shown when reporting with --group-by
+type GroupSummary struct {
+	Name       string `json:"name"`
+	Branches   int    `json:"branches"`
+	Candidates int    `json:"candidates"`
+	DiffSize   int    `json:"diffSize"`
+}
+
+var ageBuckets = []struct {
+	name   string
+	maxAge time.Duration
+}{
+	{"under a week", 7 * 24 * time.Hour},
+	{"under a month", 30 * 24 * time.Hour},
+	{"under 3 months", 90 * 24 * time.Hour},
+	{"under a year", 365 * 24 * time.Hour},
+	{"over a year", 0},
+}
+
+// branchPrefixGroup returns the naming-convention prefix of a branch, e.g.
+// feature/foo -> feature/, and user/alex/foo -> user/alex/
+func branchPrefixGroup(branch string) string {
+	parts := strings.Split(branch, "/")
+	switch {
+	case len(parts) == 1:
+		return "(no prefix)"
+	case parts[0] == "user" || parts[0] == "users":
+		if len(parts) > 2 {
+			return parts[0] + "/" + parts[1] + "/"
+		}
+	}
+	return parts[0] + "/"
+}
+
+// getBranchTipAuthors returns the author of each branch's tip commit
+func getBranchTipAuthors() (map[string]string, error) {
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname) %(authorname)", branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	authors := map[string]string{}
+	for _, line := range lines {
+		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(parts) == 2 {
+			authors[strings.TrimPrefix(parts[0], branchPrefix)] = parts[1]
+		}
+	}
+	return authors, nil
+}
+
+// groupBranchReports assigns each branch to a group (by prefix, author,
+// age-bucket, or owner), reorders the reports so groups are contiguous (preserving the
+// existing order within each group), and returns the groups in report order
+func groupBranchReports(reports []*BranchReport, groupBy string) ([]*GroupSummary, error) {
+	groupRank := map[string]string{} // sort key of each group
+	switch groupBy {
+	case "author":
+		authors, err := getBranchTipAuthors()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range reports {
+			r.Group = authors[r.Branch]
+		}
+	case "owner":
+		for _, r := range reports {
+			r.Group = "(no owner)"
+			if len(r.Owners) > 0 {
+				r.Group = strings.Join(r.Owners, " ")
+			}
+		}
+	case "age-bucket":
+		tipTimes, err := getBranchTipTimes()
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, r := range reports {
+			age := now.Sub(time.Unix(tipTimes[r.Branch], 0))
+			for i, bucket := range ageBuckets {
+				if bucket.maxAge == 0 || age < bucket.maxAge {
+					r.Group = bucket.name
+					groupRank[bucket.name] = strconv.Itoa(i)
+					break
+				}
+			}
+		}
+	default:
+		for _, r := range reports {
+			r.Group = branchPrefixGroup(r.Branch)
+		}
+	}
+	rank := func(group string) string {
+		if r, ok := groupRank[group]; ok {
+			return r
+		}
+		return group
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return rank(reports[i].Group) < rank(reports[j].Group)
+	})
+
+	groups := []*GroupSummary{}
+	for _, r := range reports {
+		if len(groups) == 0 || groups[len(groups)-1].Name != r.Group {
+			groups = append(groups, &GroupSummary{Name: r.Group})
+		}
+		g := groups[len(groups)-1]
+		g.Branches++
+		if isCandidate(r.Classification) {
+			g.Candidates++
+		}
+		if r.PotentialMerge != nil {
+			g.DiffSize += r.DiffSize
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DirectoryDebt is the unmerged work touching a directory
+type DirectoryDebt struct {
+	Directory    string   `json:"directory"`
+	Branches     []string `json:"branches"`
+	Files        int      `json:"files"`        // distinct files changed
+	LinesChanged int      `json:"linesChanged"` // lines added plus removed, summed over branches
+}
+
+// isInFlight returns true for branches holding work which hasn't been merged
+func isInFlight(classification string) bool {
+	switch classification {
+	case classUnmerged, classRebased, classUnknown:
+		return true
+	}
+	return false
+}
+
+// getChangedFiles returns the lines changed in each file matching pathspecs
+// (or every file) between two commits, counting binary files as a single line
+func getChangedFiles(start, end string, pathspecs []string) (map[string]int, error) {
+	args := []string{"git", "--no-pager", "diff", "--numstat", "--no-renames", start + ".." + end, "--"}
+	lines, err := runCommandSplitLines(append(args, pathspecs...)...)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]int{}
+	for _, line := range lines {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		added, errAdded := strconv.Atoi(parts[0])
+		removed, errRemoved := strconv.Atoi(parts[1])
+		if errAdded != nil || errRemoved != nil {
+			added, removed = 1, 0 // binary files show as "-"
+		}
+		files[parts[2]] += added + removed
+	}
+	return files, nil
+}
+
+// directoryAtDepth truncates a file's directory to at most depth components
+func directoryAtDepth(file string, depth int) string {
+	dir := path.Dir(file)
+	if dir == "." {
+		return "."
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/") + "/"
+}
+
+// getBranchDebt totals the unmerged branches' changes (under scope, when given) by directory, most changed first
+func getBranchDebt(report *Report, baseRev, scope string, depth int) ([]*DirectoryDebt, error) {
+	debt := map[string]*DirectoryDebt{}
+	files := map[string]map[string]bool{}
+	for _, b := range report.Branches {
+		if !isInFlight(b.Classification) {
+			continue
+		}
+		ref := branchRef(b.Branch)
+		mergeBase, err := getGitMergeBase(baseRev, ref)
+		if err != nil {
+			continue // unrelated history
+		}
+		changed, err := getChangedFiles(mergeBase, ref, scopePathspecs(scope))
+		if err != nil {
+			return nil, err
+		}
+		for file, lines := range changed {
+			dir := directoryAtDepth(file, depth)
+			d := debt[dir]
+			if d == nil {
+				d = &DirectoryDebt{Directory: dir, Branches: []string{}}
+				debt[dir] = d
+				files[dir] = map[string]bool{}
+			}
+			if len(d.Branches) == 0 || d.Branches[len(d.Branches)-1] != b.Branch {
+				d.Branches = append(d.Branches, b.Branch)
+			}
+			files[dir][file] = true
+			d.LinesChanged += lines
+		}
+	}
+
+	dirs := []*DirectoryDebt{}
+	for dir, d := range debt {
+		d.Files = len(files[dir])
+		dirs = append(dirs, d)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].LinesChanged != dirs[j].LinesChanged {
+			return dirs[i].LinesChanged > dirs[j].LinesChanged
+		}
+		return dirs[i].Directory < dirs[j].Directory
+	})
+	return dirs, nil
+}
+
+// showHeatmap reports which directories the unmerged branches touch the most; nothing is deleted
+func showHeatmap(rs *runState, depth int) error {
+	report := rs.analyze()
+	dirs, err := getBranchDebt(report, rs.baseRev, rs.scope, depth)
+	if err != nil {
+		return err
+	}
+	if reportJSON {
+		return writeJSON(os.Stdout, dirs)
+	}
+	reportf("%-40s %8s %6s %s\n", T("directory"), T("lines"), T("files"), T("branches"))
+	for _, d := range dirs {
+		reportf("%-40s %8d %6d %d (%s)\n", d.Directory, d.LinesChanged, d.Files, len(d.Branches), strings.Join(d.Branches, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const reviewHookFileName = "review-hook.json"
+
+// ReviewHookEvidence is written to a branch-cleanup.reviewCommand's stdin
+type ReviewHookEvidence struct {
+	*BranchReport
+	Base        string `json:"base"`
+	AuthorName  string `json:"authorName"`
+	AuthorEmail string `json:"authorEmail"`
+}
+
+// runReviewHook runs the branch-cleanup.reviewCommand (if configured) for a
+// branch which needs review, e.g. to file an issue assigned to its author.
+// Each branch tip is only handed to the command once, so reruns don't file
+// duplicate issues.
+func runReviewHook(command, base string, b *BranchReport) error {
+	path, err := getStateFilePath(reviewHookFileName)
+	if err != nil {
+		return err
+	}
+	done := map[string]time.Time{} // "branch tip" -> when the command ran
+	if err := readJSONFile(path, &done); err != nil {
+		return err
+	}
+	key := b.Branch + " " + b.TipSha
+	if _, ok := done[key]; ok {
+		return nil
+	}
+
+	author, err := runCommandSplitLines("git", "log", "-1", "--format=%an%n%ae", branchRef(b.Branch), "--")
+	if err != nil || len(author) != 2 {
+		return fmt.Errorf("failed to get the author of %s: %v", b.Branch, err)
+	}
+	evidence, err := json.Marshal(&ReviewHookEvidence{BranchReport: b, Base: base, AuthorName: author[0], AuthorEmail: author[1]})
+	if err != nil {
+		return err
+	}
+
+	debugf("running branch-cleanup.reviewCommand for %s", b.Branch)
+	acquireProc()
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(evidence)
+	cmd.Stdout = os.Stderr // keep stdout for the report
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"BRANCH_CLEANUP_BRANCH="+b.Branch,
+		"BRANCH_CLEANUP_BASE="+base,
+		"BRANCH_CLEANUP_TIP="+b.TipSha,
+		"BRANCH_CLEANUP_MATCHED="+b.MatchedSha,
+		fmt.Sprintf("BRANCH_CLEANUP_SUBJECT_SCORE=%f", b.SubjectScore),
+		fmt.Sprintf("BRANCH_CLEANUP_DIFF_SCORE=%f", b.DiffScore),
+		"BRANCH_CLEANUP_DIFF_CMD="+b.DiffCmd,
+		"BRANCH_CLEANUP_AUTHOR_NAME="+author[0],
+		"BRANCH_CLEANUP_AUTHOR_EMAIL="+author[1],
+		"BRANCH_CLEANUP_OWNERS="+strings.Join(b.Owners, " "),
+	)
+	err = cmd.Run()
+	releaseProc()
+	if err != nil {
+		return fmt.Errorf("branch-cleanup.reviewCommand failed for %s: %w", b.Branch, err)
+	}
+
+	done[key] = time.Now()
+	return writeJSONFile(path, done)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// ignorePaths are globs (from --ignore-paths) of the files left out of the
+// diffs which are compared, e.g. lockfiles, which are routinely regenerated
+// when merging, so they differ between otherwise identical changes. A glob
+// without a slash matches the file's name in any directory, like in gitignore.
+var ignorePaths []string
+
+// ignoreAttribute is the gitattribute with which repos declare the files left
+// out of the diffs compared (e.g. generated code), so the policy is versioned
+// with the repo rather than in each user's flags:
+//
+//	gen/** branch-cleanup-ignore
+const ignoreAttribute = "branch-cleanup-ignore"
+
+var ignoreAttributeCache = map[string]bool{}
+
+// getIgnoredByAttribute returns which of the files have ignoreAttribute set
+func getIgnoredByAttribute(files []string) (map[string]bool, error) {
+	unknown := []string{}
+	for _, file := range files {
+		if _, ok := ignoreAttributeCache[file]; !ok {
+			unknown = append(unknown, file)
+		}
+	}
+	if len(unknown) > 0 {
+		out, err := runCommandTrimmedOutput(append([]string{"git", "check-attr", "-z", ignoreAttribute, "--"}, unknown...)...)
+		if err != nil {
+			return nil, err
+		}
+		// <path> NUL <attribute> NUL <value> NUL
+		fields := strings.Split(out, "\x00")
+		for i := 0; i+2 < len(fields); i += 3 {
+			ignoreAttributeCache[fields[i]] = fields[i+2] == "set"
+		}
+	}
+	ignored := map[string]bool{}
+	for _, file := range files {
+		ignored[file] = ignoreAttributeCache[file]
+	}
+	return ignored, nil
+}
+
+func isIgnoredPath(file string) bool {
+	for _, glob := range ignorePaths {
+		name := file
+		if !strings.Contains(glob, "/") {
+			name = path.Base(file)
+		}
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredPathspecs returns pathspecs excluding ignorePaths and the files with
+// ignoreAttribute set, for git commands measuring diffs
+func ignoredPathspecs() []string {
+	pathspecs := []string{":(exclude,attr:" + ignoreAttribute + ")"}
+	for _, glob := range ignorePaths {
+		if glob == "" {
+			continue
+		}
+		if !strings.Contains(glob, "/") {
+			glob = "**/" + glob
+		}
+		pathspecs = append(pathspecs, ":(exclude,glob)"+glob)
+	}
+	return pathspecs
+}
+
+// diffPaths returns the paths of a "diff --git a/<path> b/<path>" line
+func diffPaths(line string) []string {
+	return strings.SplitN(strings.TrimPrefix(line, "diff --git a/"), " b/", 2)
+}
+
+// withoutIgnoredFiles drops the changes to ignorePaths, and to the files with
+// ignoreAttribute set, from a diff
+func withoutIgnoredFiles(diff string) (string, error) {
+	lines := strings.Split(diff, "\n")
+	files := []string{}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "diff --git a/") {
+			files = append(files, diffPaths(line)...)
+		}
+	}
+	byAttribute, err := getIgnoredByAttribute(files)
+	if err != nil {
+		return "", err
+	}
+	isIgnored := func(file string) bool {
+		return byAttribute[file] || isIgnoredPath(file)
+	}
+
+	kept := lines[:0]
+	ignored := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "diff --git a/") {
+			paths := diffPaths(line)
+			ignored = isIgnored(paths[0]) || len(paths) == 2 && isIgnored(paths[1])
+		}
+		if !ignored {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n"), nil
+}
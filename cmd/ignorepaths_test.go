@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// setIgnorePaths sets ignorePaths for the rest of the test
+func setIgnorePaths(t *testing.T, globs ...string) {
+	saved := ignorePaths
+	ignorePaths = globs
+	t.Cleanup(func() { ignorePaths = saved })
+}
+
+func TestIsIgnoredPath(t *testing.T) {
+	setIgnorePaths(t, "package-lock.json", "*.lock", "gen/*.go", "")
+	tests := []struct {
+		file    string
+		ignored bool
+	}{
+		{"package-lock.json", true},
+		{"web/app/package-lock.json", true}, // a glob without a slash matches names in any directory
+		{"Cargo.lock", true},
+		{"vendor/deps/yarn.lock", true},
+		{"gen/api.go", true},
+		{"gen/v1/api.go", false}, // * doesn't cross a slash
+		{"src/gen/api.go", false},
+		{"package.json", false},
+		{"lock", false},
+		{"main.go", false},
+	}
+	for _, test := range tests {
+		if got := isIgnoredPath(test.file); got != test.ignored {
+			t.Errorf("isIgnoredPath(%q) = %v, expected %v", test.file, got, test.ignored)
+		}
+	}
+
+	setIgnorePaths(t)
+	if isIgnoredPath("package-lock.json") {
+		t.Errorf("a file is ignored without any --ignore-paths")
+	}
+}
+
+func TestIgnoredPathspecs(t *testing.T) {
+	tests := []struct {
+		globs     []string
+		pathspecs []string
+	}{
+		{nil, []string{":(exclude,attr:branch-cleanup-ignore)"}},
+		{[]string{"*.lock"}, []string{":(exclude,attr:branch-cleanup-ignore)", ":(exclude,glob)**/*.lock"}},
+		{[]string{"gen/*.go", ""}, []string{":(exclude,attr:branch-cleanup-ignore)", ":(exclude,glob)gen/*.go"}},
+	}
+	for _, test := range tests {
+		setIgnorePaths(t, test.globs...)
+		if got := ignoredPathspecs(); !reflect.DeepEqual(got, test.pathspecs) {
+			t.Errorf("ignoredPathspecs() with %q = %q, expected %q", test.globs, got, test.pathspecs)
+		}
+	}
+}
+
+func TestDiffPaths(t *testing.T) {
+	tests := []struct {
+		line  string
+		paths []string
+	}{
+		{"diff --git a/main.go b/main.go", []string{"main.go", "main.go"}},
+		{"diff --git a/old/name.go b/new/name.go", []string{"old/name.go", "new/name.go"}},
+		{"diff --git a/with space.txt b/with space.txt", []string{"with space.txt", "with space.txt"}},
+	}
+	for _, test := range tests {
+		if got := diffPaths(test.line); !reflect.DeepEqual(got, test.paths) {
+			t.Errorf("diffPaths(%q) = %q, expected %q", test.line, got, test.paths)
+		}
+	}
+}
+
+func TestWithoutIgnoredFiles(t *testing.T) {
+	r := newTestRepo(t)
+	r.write(".gitattributes", "gen/** branch-cleanup-ignore\n")
+	r.enter()
+	setIgnorePaths(t, "*.lock")
+	savedCache := ignoreAttributeCache
+	ignoreAttributeCache = map[string]bool{}
+	t.Cleanup(func() { ignoreAttributeCache = savedCache })
+
+	diff := `diff --git a/main.go b/main.go
++kept
+diff --git a/gen/api.go b/gen/api.go
++generated
+diff --git a/web/yarn.lock b/web/yarn.lock
++locked
+diff --git a/gen/moved.go b/src/moved.go
++renamed out of gen
+diff --git a/f1.txt b/f1.txt
++also kept`
+	got, err := withoutIgnoredFiles(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `diff --git a/main.go b/main.go
++kept
+diff --git a/f1.txt b/f1.txt
++also kept`
+	if got != expected {
+		t.Errorf("withoutIgnoredFiles kept:\n%s\nexpected:\n%s", got, expected)
+	}
+}
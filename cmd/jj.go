@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func getGitTopLevel() (string, error) {
+	return runCommandTrimmedOutput("git", "rev-parse", "--show-toplevel")
+}
+
+// isJujutsuColocated returns true when a jj repo shares this git store
+// (i.e. a .jj directory lives next to .git)
+func isJujutsuColocated() bool {
+	topLevel, err := getGitTopLevel()
+	if err != nil {
+		return false
+	}
+	return pathExists(topLevel + "/.jj")
+}
+
+// getJujutsuActiveBookmarks returns the bookmarks pointing at the jj working-copy
+// commit or its parent; these are being actively worked on and must not be deleted.
+func getJujutsuActiveBookmarks() (map[string]bool, error) {
+	lines, err := runCommandSplitLines("jj", "bookmark", "list", "--revisions", "@ | @-", "--template", `name ++ "\n"`)
+	if err != nil {
+		return nil, err
+	}
+	bookmarks := map[string]bool{}
+	for _, line := range lines {
+		bookmark := strings.TrimSpace(line)
+		if bookmark != "" {
+			bookmarks[bookmark] = true
+		}
+	}
+	return bookmarks, nil
+}
+
+// forgetJujutsuBookmark has jj forget the bookmark (which also removes the
+// colocated git branch), rather than deleting the git branch behind jj's back,
+// which jj would later import as a bookmark deletion to push.
+func forgetJujutsuBookmark(branchName string) error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	infof(T("forgetting jj bookmark %s\n"), branchName)
+	// jj treats bookmark arguments as patterns; exact: matches the name literally
+	return runCommand("jj", "bookmark", "forget", "exact:"+branchName)
+}
+
+// getJujutsuBaseBranch is used when HEAD is detached (as jj leaves it) to find
+// which of the usual base branches exists.
+func getJujutsuBaseBranch(branches []string) (string, error) {
+	for _, candidate := range []string{"main", "master", "trunk"} {
+		for _, branch := range branches {
+			if branch == candidate {
+				return branch, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("HEAD is detached and no main, master, or trunk branch exists")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Operational logging goes to stderr, keeping stdout for the report itself.
+
+const (
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+	logLevelWarn  = "warn"
+	logLevelError = "error"
+)
+
+var (
+	logJSON    bool
+	logVerbose bool
+	logQuiet   bool // drops info and warnings, e.g. about selftest's scratch repo; errors are always logged
+)
+
+type logEntry struct {
+	Time  string `json:"time"`
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+}
+
+func logf(level, format string, args ...interface{}) {
+	if level == logLevelDebug && !logVerbose {
+		return
+	}
+	if logQuiet && (level == logLevelInfo || level == logLevelWarn) {
+		return
+	}
+	msg := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	if !logJSON {
+		fmt.Fprintf(os.Stderr, "%s\n", msg)
+		return
+	}
+	line, err := json.Marshal(logEntry{
+		Time:  time.Now().UTC().Format(time.RFC3339Nano),
+		Level: level,
+		Msg:   msg,
+	})
+	if err != nil {
+		panic(err) // logEntry always marshals
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", line)
+}
+
+func debugf(format string, args ...interface{}) {
+	logf(logLevelDebug, format, args...)
+}
+
+func infof(format string, args ...interface{}) {
+	logf(logLevelInfo, format, args...)
+}
+
+func warnf(format string, args ...interface{}) {
+	logf(logLevelWarn, format, args...)
+}
+
+func errorf(format string, args ...interface{}) {
+	logf(logLevelError, format, args...)
+}
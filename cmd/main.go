@@ -5,21 +5,26 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hyperjumptech/beda"
 	"github.com/jessevdk/go-flags"
 )
 
 func die(msg string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, msg, args...)
-	os.Exit(1)
+	dieWithCode(exitFailure, msg, args...)
 }
 
 func runCommandTrimmedOutput(args ...string) (string, error) {
 	if len(args) == 0 {
 		return "", fmt.Errorf("no command given")
 	}
+	debugf("running %s", strings.Join(args, " "))
+	acquireProc()
+	defer releaseProc()
 	cmd := exec.Command(args[0], args[1:]...)
 	out, err := cmd.Output()
 	if err != nil {
@@ -27,6 +32,18 @@ func runCommandTrimmedOutput(args ...string) (string, error) {
 	}
 	return strings.TrimSpace(string(out)), nil
 }
+
+func runCommand(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given")
+	}
+	debugf("running %s", strings.Join(args, " "))
+	acquireProc()
+	defer releaseProc()
+	cmd := exec.Command(args[0], args[1:]...)
+	return cmd.Run()
+}
+
 func runCommandSplitLines(args ...string) ([]string, error) {
 	out, err := runCommandTrimmedOutput(args...)
 	if err != nil {
@@ -35,7 +52,20 @@ func runCommandSplitLines(args ...string) ([]string, error) {
 	return strings.Split(out, "\n"), nil
 }
 
-const branchPrefix = "refs/heads/"
+// branchPrefix is where branches are, within the active namespace (see refNamespace)
+var branchPrefix = refNamespace + "refs/heads/"
+
+func branchRef(branch string) string {
+	return branchPrefix + branch
+}
+
+// shellQuote quotes s for use in the shell commands we suggest to the user
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./^") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
 
 func getBranches() ([]string, error) {
 	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname)", branchPrefix)
@@ -53,7 +83,7 @@ func getBranches() ([]string, error) {
 }
 
 func getGitRevParse(s string) (string, error) {
-	return runCommandTrimmedOutput("git", "rev-parse", s)
+	return runCommandTrimmedOutput("git", "rev-parse", "--verify", "--quiet", s)
 }
 
 func getGitMergeBase(a, b string) (string, error) {
@@ -61,19 +91,27 @@ func getGitMergeBase(a, b string) (string, error) {
 }
 
 func getCommitSubject(commit string) (string, error) {
-	return runCommandTrimmedOutput("git", "--no-pager", "show", "--format=format:%s", "-s", commit)
+	return runCommandTrimmedOutput("git", "--no-pager", "show", "--format=format:%s", "-s", commit, "--")
+}
+
+// resolveBase returns the commit sha of base, which may be a branch, tag, or any other committish
+func resolveBase(base string) (string, error) {
+	if _, err := getGitRevParse(branchRef(base)); err == nil {
+		base = branchRef(base) // prefer a branch over a tag of the same name
+	}
+	return runCommandTrimmedOutput("git", "rev-parse", "--verify", "--quiet", "--end-of-options", base+"^{commit}")
 }
 
 func getCurrentBranch() (string, error) {
-	s, err := runCommandTrimmedOutput("git", "symbolic-ref", "HEAD")
+	s, err := runCommandTrimmedOutput("git", "symbolic-ref", refNamespace+"HEAD")
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimPrefix(s, "refs/heads/"), nil
+	return strings.TrimPrefix(s, branchPrefix), nil
 }
 
 func getCommitDiffOnly(commit string) (string, error) {
-	contents, err := runCommandTrimmedOutput("git", "--no-pager", "show", commit)
+	contents, err := runCommandTrimmedOutput("git", "--no-pager", "show", commit, "--")
 	if err != nil {
 		return "", err
 	}
@@ -115,14 +153,17 @@ type CommitDiff struct {
 }
 
 var CommitDiffCache map[string]*CommitDiff
+var commitDiffCacheHits, commitDiffCacheMisses int
 
 func getCommitDiff(commit string) (*CommitDiff, error) {
 	if CommitDiffCache == nil {
 		CommitDiffCache = map[string]*CommitDiff{}
 	}
 	if commitDiff, ok := CommitDiffCache[commit]; ok {
+		commitDiffCacheHits++
 		return commitDiff, nil
 	}
+	commitDiffCacheMisses++
 	var commitDiff CommitDiff
 	var err error
 	commitDiff.Sha = commit
@@ -144,7 +185,29 @@ func getCommitDiff(commit string) (*CommitDiff, error) {
 
 // NOTE: this does not return the start commit, but DOES include the end commit
 func getCommits(start, end string) ([]string, error) {
-	lines, err := runCommandSplitLines("git", "log", "--format=format:%H", start+".."+end)
+	return getCommitsInRange(start, end, false, nil)
+}
+
+// allowPredatingMatches lets branches match base commits which don't descend
+// from their merge-base, i.e. which landed on a side branch before the branch diverged
+var allowPredatingMatches bool
+
+// getBaseCommits returns the base commits which a branch which diverged at
+// start may have been merged as. Unless allowPredatingMatches is set, these
+// only include commits descending from start; the start..end range also
+// includes older commits which a merge brought into the base. When pathspecs
+// are given, only commits touching them are returned.
+func getBaseCommits(start, end string, pathspecs []string) ([]string, error) {
+	return getCommitsInRange(start, end, !allowPredatingMatches, pathspecs)
+}
+
+func getCommitsInRange(start, end string, ancestryPath bool, pathspecs []string) ([]string, error) {
+	args := []string{"git", "log", "--format=format:%H"}
+	if ancestryPath {
+		args = append(args, "--ancestry-path")
+	}
+	args = append(args, start+".."+end, "--")
+	lines, err := runCommandSplitLines(append(args, pathspecs...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -161,51 +224,77 @@ func getCommits(start, end string) ([]string, error) {
 // git --no-pager show HEAD is equivalent to git --no-pager diff HEAD^..HEAD **except** show will also show the commit time/author/subject/message details
 // Note that this combines the diffs of commits from start to end INCLUSIVE
 func getGitDiff(start, end string) (string, error) {
-	return runCommandTrimmedOutput("git", "--no-pager", "diff", start+".."+end)
+	return runCommandTrimmedOutput("git", "--no-pager", "diff", start+".."+end, "--")
 }
 
 type PotentialMerge struct {
-	Branch       string
-	MergedSha    string
-	Merged       bool // true when the branch sha matches the merged sha (i.e. no rewritten history)
-	SubjectScore float32
-	DiffScore    float32
-	DiffSize     int
-	NumCommits   int
-	DiffCmd      string
+	Branch          string   `json:"-"`
+	MergedSha       string   `json:"mergedSha"`
+	Merged          bool     `json:"merged"`               // true when the branch sha matches the merged sha (i.e. no rewritten history)
+	AtBaseTip       bool     `json:"atBaseTip"`            // true when the branch points at the same commit as the base (no commits of its own)
+	MatchedSha      string   `json:"matchedSha,omitempty"` // the base commit which best matches the branch
+	TreeIdentical   bool     `json:"treeIdentical"`        // true when the branch tip's tree is the same as the matched commit's tree
+	SubjectScore    float32  `json:"subjectScore"`
+	DiffScore       float32  `json:"diffScore"`
+	DiffSize        int      `json:"diffSize"`
+	NumCommits      int      `json:"numCommits"`
+	DiffCmd         string   `json:"diffCmd,omitempty"`
+	RebasedUpstream string   `json:"rebasedUpstream,omitempty"` // set when the branch was rebased onto this (still active) upstream
+	Empty           bool     `json:"empty"`                     // true when the branch only contains empty commits
+	TipSha          string   `json:"tipSha"`                    // the branch tip at the time of analysis
+	MergeBase       string   `json:"mergeBase"`                 // where the branch diverged from the base
+	StatScore       float32  `json:"statScore"`                 // how alike the sizes (files, insertions, deletions) of the branch and matched commit are
+	CommitDiffCmds  []string `json:"commitDiffCmds,omitempty"`  // for multi-commit branches, compares each commit with its closest base commit
+	Revert          bool     `json:"revert,omitempty"`          // true when the branch reverts an earlier change, so it was only compared with the base's reverts (see isRevertBranch)
 }
 
-func findMerged(currentBranch, branch string) (*PotentialMerge, error) {
+// findMerged compares branch against the commits on baseRev (a full ref or sha) since they diverged
+func findMerged(baseRev, branch string) (*PotentialMerge, error) {
 	var highestSubjectScore float32
 	var highestDiff *CommitDiff
 
-	base, err := getGitMergeBase(currentBranch, branch)
+	// always refer to branches by their full ref, so names can never be mistaken for options, paths, or other refs
+	baseRef := baseRev
+	ref := branchRef(branch)
+
+	base, err := getGitMergeBase(baseRef, ref)
+	if isExitStatus(err, 1) {
+		return nil, nil // unrelated history, so nothing can match
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	branchSha, err := getGitRevParse(branch)
+	branchSha, err := getGitRevParse(ref)
 	if err != nil {
 		return nil, err
 	}
 
 	if base == branchSha {
+		baseSha, err := getGitRevParse(baseRef)
+		if err != nil {
+			return nil, err
+		}
 		return &PotentialMerge{
 			Branch:       branch,
+			TipSha:       branchSha,
+			MergeBase:    base,
 			MergedSha:    base,
 			Merged:       true,
+			AtBaseTip:    base == baseSha,
 			SubjectScore: 1.00,
 			DiffScore:    1.00,
 			NumCommits:   0,
 		}, nil
 	}
 
-	branchCommits, err := getCommits(base, branch)
+	branchCommits, err := getCommits(base, ref)
 	if err != nil {
 		return nil, err
 	}
 	if len(branchCommits) == 0 {
-		panic("branchCommits is empty, but if base == branchSha check didnt catch this")
+		// only possible when replace refs or grafts alter the history git log walks
+		return nil, fmt.Errorf("branch has no commits since the merge-base %s, but does not point at it (are replace refs or grafts in use?)", base)
 	}
 
 	var combinedDiff string
@@ -216,42 +305,167 @@ func findMerged(currentBranch, branch string) (*PotentialMerge, error) {
 		return nil, err
 	}
 	if len(branchCommits) > 1 {
-		combinedDiff, err = getGitDiff(base, branch)
+		combinedDiff, err = getGitDiff(base, ref)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	commits, err := getCommits(base, currentBranch)
+	// a branch of only empty commits (e.g. from --allow-empty) has nothing to compare
+	if (len(branchCommits) == 1 && strings.TrimSpace(branchDiff.Diff) == "") || (len(branchCommits) > 1 && combinedDiff == "") {
+		return &PotentialMerge{
+			Branch:     branch,
+			TipSha:     branchSha,
+			MergeBase:  base,
+			MergedSha:  branchDiff.Sha,
+			Empty:      true,
+			NumCommits: len(branchCommits),
+		}, nil
+	}
+
+	commits, err := getBaseCommits(base, baseRef, sparsePathspecs[branch])
 	if err != nil {
 		return nil, err
 	}
+	var commitTimes map[string]time.Time
+	var outsideWindow *CommitDiff
+	var outsideWindowScore float32
+	if matchWindow > 0 {
+		commitTimes, err = getCommitTimes(base, baseRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var branchStat *ShortStat
+	if len(branchCommits) == 1 {
+		branchStat, err = getCommitShortStat(branchCommits[0])
+	} else {
+		branchStat, err = getShortStat(base, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// closerStat breaks ties between equally good subjects (e.g. two "fix tests" commits) by the sizes of their changes
+	closerStat := func(commit, than string) (bool, error) {
+		a, err := getCommitShortStat(commit)
+		if err != nil {
+			return false, err
+		}
+		b, err := getCommitShortStat(than)
+		if err != nil {
+			return false, err
+		}
+		return branchStat.similarity(a) > branchStat.similarity(b), nil
+	}
+
+	revert := isRevertBranch(branch, branchCommits, branchDiff)
+	var reverted string
+	if revert {
+		if reverted, err = getRevertedCommit(branchDiff.Sha); err != nil {
+			return nil, err
+		}
+	}
+
+	branchSubject := normalizeSubject(branchDiff.Subject)
+	candidates := []*CommitDiff{} // the base commits within the match window
 	for _, commit := range commits {
 		commitDiff, err := getCommitDiff(commit)
 		if err != nil {
 			return nil, err
 		}
+		if revert && !isRevertSubject(normalizeSubject(commitDiff.Subject)) {
+			continue // a revert is never the change it reverts
+		}
 
-		sd := beda.NewStringDiff(branchDiff.Subject, commitDiff.Subject)
+		sd := beda.NewStringDiff(branchSubject, normalizeSubject(commitDiff.Subject))
 		subjectScore := sd.JaroWinklerDistance(0.1)
+		if reverted != "" {
+			// a base commit reverting the same commit is the counterpart, however its subject was edited
+			baseReverted, err := getRevertedCommit(commit)
+			if err != nil {
+				return nil, err
+			}
+			if baseReverted == reverted {
+				subjectScore = 1.0
+			}
+		}
+
+		if matchWindow > 0 && time.Since(commitTimes[commit]) > matchWindow {
+			if subjectScore > outsideWindowScore {
+				outsideWindowScore = subjectScore
+				outsideWindow = commitDiff
+			}
+			continue
+		}
 
-		if subjectScore > highestSubjectScore {
+		better := subjectScore > highestSubjectScore
+		if !better && subjectScore == highestSubjectScore && highestDiff != nil {
+			if better, err = closerStat(commit, highestDiff.Sha); err != nil {
+				return nil, err
+			}
+		}
+		candidates = append(candidates, commitDiff)
+		if better {
 			highestSubjectScore = subjectScore
 			highestDiff = commitDiff
 			highestCombinedDiff = combinedDiff
 		}
 	}
 
+	if outsideWindow != nil && outsideWindowScore > highestSubjectScore {
+		warnf(T("the best match for %s, %s, is older than --match-window; ignoring it\n"), branch, outsideWindow.Sha)
+	}
+
 	if highestDiff == nil {
 		return nil, nil
 	}
 
+	highestStat, err := getCommitShortStat(highestDiff.Sha)
+	if err != nil {
+		return nil, err
+	}
+	statScore := branchStat.similarity(highestStat)
+	if statScore < minStatScore {
+		// changes of such different sizes can't be the same, so the expensive full diff comparison is skipped
+		diffSize := len(branchDiff.Diff)
+		if len(branchCommits) > 1 {
+			diffSize = len(combinedDiff)
+		}
+		return &PotentialMerge{
+			Branch:       branch,
+			TipSha:       branchSha,
+			MergeBase:    base,
+			MergedSha:    branchDiff.Sha,
+			MatchedSha:   highestDiff.Sha,
+			SubjectScore: highestSubjectScore,
+			StatScore:    statScore,
+			Revert:       revert,
+			DiffSize:     diffSize,
+			NumCommits:   len(branchCommits),
+		}, nil
+	}
+
 	var diffScore float32
 	if highestCombinedDiff == "" {
 
 		// check that the diff contents match too
-		sd := beda.NewStringDiff(branchDiff.Diff, highestDiff.Diff)
-		diffScore = sd.JaroWinklerDistance(0.1)
+		if diffEngine == engineNumstat {
+			diffScore, err = numstatScore(branchDiff.Sha+"^", branchDiff.Sha, highestDiff.Sha)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			a, err := withoutIgnoredFiles(branchDiff.Diff)
+			if err != nil {
+				return nil, err
+			}
+			b, err := withoutIgnoredFiles(highestDiff.Diff)
+			if err != nil {
+				return nil, err
+			}
+			sd := beda.NewStringDiff(a, b)
+			diffScore = sd.JaroWinklerDistance(0.1)
+		}
 
 		if 1 != len(branchCommits) {
 			panic("expected single commit")
@@ -259,12 +473,17 @@ func findMerged(currentBranch, branch string) (*PotentialMerge, error) {
 
 		return &PotentialMerge{
 			Branch:       branch,
+			TipSha:       branchSha,
+			MergeBase:    base,
 			MergedSha:    branchDiff.Sha,
+			MatchedSha:   highestDiff.Sha,
 			SubjectScore: highestSubjectScore,
+			StatScore:    statScore,
+			Revert:       revert,
 			DiffScore:    diffScore,
 			DiffSize:     len(branchDiff.Diff),
 			NumCommits:   1,
-			DiffCmd:      fmt.Sprintf("meld <(git show %s) <(git show %s)", branch, highestDiff.Sha),
+			DiffCmd:      fmt.Sprintf("meld <(git show %s) <(git show %s)", shellQuote(ref), highestDiff.Sha),
 		}, nil
 	}
 
@@ -275,32 +494,162 @@ func findMerged(currentBranch, branch string) (*PotentialMerge, error) {
 		return nil, err
 	}
 
-	sd := beda.NewStringDiff(combinedDiff, highestCombinedDiff)
-	diffScore = sd.JaroWinklerDistance(0.1)
+	if diffEngine == engineNumstat {
+		diffScore, err = numstatScore(base, ref, highestDiff.Sha)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		a, err := withoutIgnoredFiles(combinedDiff)
+		if err != nil {
+			return nil, err
+		}
+		b, err := withoutIgnoredFiles(highestCombinedDiff)
+		if err != nil {
+			return nil, err
+		}
+		sd := beda.NewStringDiff(a, b)
+		diffScore = sd.JaroWinklerDistance(0.1)
+	}
+
+	commitDiffCmds, err := getCommitDiffCmds(branchCommits, candidates)
+	if err != nil {
+		return nil, err
+	}
 
 	return &PotentialMerge{
-		Branch:       branch,
-		MergedSha:    branchDiff.Sha,
-		SubjectScore: highestSubjectScore,
-		DiffScore:    diffScore,
-		DiffSize:     len(combinedDiff),
-		NumCommits:   len(branchCommits),
-		DiffCmd:      fmt.Sprintf("meld <(git --no-pager diff %s..%s) <(git --no-pager diff %s..%s)", base, branch, highestDiff.Sha+"^", highestDiff.Sha),
+		Branch:         branch,
+		TipSha:         branchSha,
+		MergeBase:      base,
+		MergedSha:      branchDiff.Sha,
+		MatchedSha:     highestDiff.Sha,
+		SubjectScore:   highestSubjectScore,
+		StatScore:      statScore,
+		Revert:         revert,
+		DiffScore:      diffScore,
+		DiffSize:       len(combinedDiff),
+		NumCommits:     len(branchCommits),
+		DiffCmd:        fmt.Sprintf("meld <(git --no-pager diff %s) <(git --no-pager diff %s..%s)", shellQuote(base+".."+ref), highestDiff.Sha+"^", highestDiff.Sha),
+		CommitDiffCmds: commitDiffCmds,
 	}, nil
 }
 
+// getCommitDiffCmds returns a command comparing each of the branch's commits
+// (oldest first) with the base commit whose subject is closest to its own; when
+// the branch was squashed, that is often the squash commit for all of them
+func getCommitDiffCmds(branchCommits []string, candidates []*CommitDiff) ([]string, error) {
+	cmds := []string{}
+	for i := len(branchCommits) - 1; i >= 0; i-- {
+		commitDiff, err := getCommitDiff(branchCommits[i])
+		if err != nil {
+			return nil, err
+		}
+		subject := normalizeSubject(commitDiff.Subject)
+		var closest *CommitDiff
+		var closestScore float32
+		for _, candidate := range candidates {
+			sd := beda.NewStringDiff(subject, normalizeSubject(candidate.Subject))
+			if score := sd.JaroWinklerDistance(0.1); closest == nil || score > closestScore {
+				closest, closestScore = candidate, score
+			}
+		}
+		if closest == nil {
+			continue
+		}
+		cmds = append(cmds, fmt.Sprintf("meld <(git show %s) <(git show %s)", commitDiff.Sha, closest.Sha))
+	}
+	return cmds, nil
+}
+
 type opts struct {
-	Verbose         bool    `long:"verbose" short:"v" description:"Enable verbose logging"`
-	Version         bool    `long:"version" short:"V" description:"Print version and exit"`
-	Perfect         bool    `long:"perfect" description:"only display perfect matches"`
-	MinSubjectScore float32 `long:"min-subject-score" default:"0.9" description:"minimum subject score"`
-	MinDiffScore    float32 `long:"min-diff-score"  default:"0.9" description:"minimum diff score"`
+	Verbose                  bool          `long:"verbose" short:"v" description:"Enable verbose logging"`
+	Version                  bool          `long:"version" short:"V" description:"Print version and exit"`
+	Perfect                  bool          `long:"perfect" description:"only display perfect matches"`
+	MinSubjectScore          float32       `long:"min-subject-score" default:"0.9" description:"minimum subject score"`
+	MinDiffScore             float32       `long:"min-diff-score"  default:"0.9" description:"minimum diff score"`
+	IgnoreStackTools         bool          `long:"ignore-stack-tools" description:"do not protect branches tracked by ghstack, graphite, or git-branchless"`
+	JJForget                 bool          `long:"jj-forget" description:"in a colocated jj repo, forget merged bookmarks via jj instead of deleting the git branch"`
+	Lang                     string        `long:"lang" description:"language of report output (en, de); defaults to $LANG"`
+	LogFormat                string        `long:"log-format" default:"text" choice:"text" choice:"json" description:"format of log messages written to stderr"`
+	Stats                    bool          `long:"stats" description:"append run statistics to .git/branch-cleanup/stats.jsonl (see the stats command)"`
+	MaxProcs                 int           `long:"max-procs" description:"maximum number of concurrent git processes (default: number of CPUs)"`
+	DeleteTrackingDuplicates bool          `long:"delete-tracking-duplicates" description:"delete branches which point at the tip of the base branch (e.g. a stable alias parked on main)"`
+	Output                   string        `long:"output" default:"text" choice:"text" choice:"json" choice:"table" description:"format of the report written to stdout; table fits the terminal's width (see --no-truncate)"`
+	Sort                     string        `long:"sort" default:"name" choice:"name" choice:"age" choice:"score" choice:"size" description:"order in which branches are reported"`
+	MaxLoad                  float64       `long:"max-load" description:"delay spawning git processes while the 1-minute load average exceeds this (0 disables)"`
+	Sample                   int           `long:"sample" description:"only analyze N branches and extrapolate the totals; nothing is deleted"`
+	SampleOrder              string        `long:"sample-order" default:"random" choice:"random" choice:"oldest" description:"which branches --sample picks"`
+	GroupBy                  string        `long:"group-by" choice:"prefix" choice:"author" choice:"age-bucket" choice:"owner" description:"group the report by branch name prefix, tip author, tip age, or CODEOWNERS owner"`
+	Limit                    int           `long:"limit" description:"analyze at most N branches; the next run resumes where this one stopped"`
+	TimeBudget               time.Duration `long:"time-budget" description:"stop analyzing once this much time (e.g. 5m) has passed; the next run resumes where this one stopped"`
+	StripEmoji               bool          `long:"strip-emoji" description:"ignore emoji and zero-width characters when comparing commit subjects"`
+	DeleteEmpty              bool          `long:"delete-empty" description:"delete branches which only contain empty commits"`
+	DeleteUpstreamGone       bool          `long:"delete-upstream-gone" description:"delete branches whose upstream was deleted from its remote (e.g. once its pull request merged), although nothing in the base matches them"`
+	Base                     string        `long:"base" description:"branch, tag, or commit to compare branches against (default: the current branch, which must be main, master, or trunk)"`
+	UseReplaceObjects        bool          `long:"use-replace-objects" description:"honor git replace refs (by default they are ignored so analysis is based on the true history)"`
+	ExportEvidence           string        `long:"export-evidence" value-name:"DIR" description:"write each candidate's branch diff, matched commit diff, and metadata under DIR"`
+	UpstreamRemote           string        `long:"upstream-remote" value-name:"REMOTE" description:"in a fork workflow, the remote whose default branch branches are merged into (default: upstream)"`
+	SyncBase                 bool          `long:"sync-base" description:"with fork-sync, also fast-forward the base branch to the upstream's, locally and on the fork"`
+	Quick                    bool          `long:"quick" description:"skip diff scoring, only reporting branches merged by ancestry or whose upstream is gone (everything else is unknown)"`
+	MatchWindow              Age           `long:"match-window" value-name:"AGE" description:"only match base commits at most this old (e.g. 6mo, 90d); older matches are almost always false positives"`
+	AllowPredatingMatches    bool          `long:"allow-predating-matches" description:"also match base commits which predate the branch, i.e. which a merge brought in from a side branch older than the branch's merge-base"`
+	DeleteDuplicates         bool          `long:"delete-duplicates" description:"delete all but one of each set of unmerged branches with the same commit or diff (e.g. foo and foo-backup)"`
+	CleanBackups             bool          `long:"clean-backups" description:"delete backup copies of branches (e.g. foo-backup, foo-old, foo-copy, foo.orig) whose commits are all in another local branch"`
+	Interactive              bool          `long:"interactive" short:"i" description:"ask before deleting each branch, then confirm the whole batch before anything is deleted (see the rollback command)"`
+	GraphFormat              string        `long:"graph-format" choice:"dot" choice:"ascii" default:"dot" description:"format of the graph command's output"`
+	HeatmapDepth             int           `long:"heatmap-depth" default:"2" description:"number of leading path components the heatmap command groups files by"`
+	GC                       bool          `long:"gc" description:"run git gc once branches have been deleted, and report how much space it freed"`
+	GCPrune                  string        `long:"gc-prune" value-name:"EXPIRE" default:"2.weeks.ago" description:"with --gc, prune unreachable objects older than this (e.g. now)"`
+	ExpireReflogs            string        `long:"expire-reflogs" value-name:"EXPIRE" optional:"yes" optional-value:"now" description:"once branches are deleted, expire the HEAD reflog entries (of every worktree) older than EXPIRE (default: now) which point at commits only the deleted branches had, so gc can free them; other entries are kept"`
+	Touches                  []string      `long:"touches" value-name:"PATHSPEC" description:"only analyze branches which change files matching this pathspec (e.g. services/payments/ or '*.proto'); may be repeated"`
+	OwnedBy                  []string      `long:"owned-by" value-name:"OWNER" description:"only analyze branches changing files which CODEOWNERS assigns to this owner (e.g. @org/payments); may be repeated"`
+	MinStatScore             float32       `long:"min-stat-score" default:"0.5" description:"skip the full diff comparison when the numbers of files, insertions, and deletions are less similar than this (0 disables)"`
+	Engine                   string        `long:"engine" choice:"jaro-winkler" choice:"numstat" default:"jaro-winkler" description:"how diffs are compared: jaro-winkler compares their full text; numstat compares the lines changed per file, which is much cheaper and ignores context churn from rebases, but never scores a perfect match"`
+	MaxCommits               int           `long:"max-commits" description:"do not analyze branches with more than this many unmerged commits, which are rarely simple squash merges; they are reported for manual review (0 disables)"`
+	Scope                    string        `long:"scope" optional:"yes" optional-value:"." value-name:"DIR" description:"only analyze and report on branches changing files under this directory (default: the current one), e.g. to clean up a monorepo team's branches from its subtree"`
+	Strict                   bool          `long:"strict" description:"abort on the first branch which can not be analyzed, instead of reporting it and carrying on"`
+	Schema                   bool          `long:"schema" description:"print the JSON schema of --output=json reports and exit"`
+	RecurseSubmodules        bool          `long:"recurse-submodules" description:"also clean up each initialized submodule, comparing its branches against its own default branch"`
+	Format                   string        `long:"format" value-name:"TEMPLATE" description:"report each branch on a line of this Go text/template, e.g. '{{.Branch}} {{.Classification}} {{.MatchedSha}}' (fields are named like the JSON report's, capitalized)"`
+	NoTruncate               bool          `long:"no-truncate" description:"never truncate --output=table columns to fit the terminal"`
+	AcceptRisk               bool          `long:"accept-risk" description:"also delete branches with elevated risk (e.g. commits after the one they matched, or an upstream with commits they lack), which are otherwise kept unless approved with --interactive"`
+	SwitchAway               bool          `long:"switch-away" description:"when a branch to delete is checked out here (possible with --base), switch to the base first (detaching HEAD when the base is not a branch)"`
+	Branch                   string        `long:"branch" value-name:"NAME" description:"with the analyze command, the branch to analyze"`
+	Stdio                    bool          `long:"stdio" description:"serve JSON-RPC requests (analyze, candidates, delete) on stdin and stdout until stdin is closed, keeping caches warm between them"`
+	IAmMaintainer            bool          `long:"i-am-maintainer" description:"run operations which branch-cleanup.allow does not permit in this repository (e.g. deleting branches on remotes)"`
+	MaxDelete                int           `long:"max-delete" default:"20" description:"stop deleting branches after this many in a run, keeping and reporting the rest, unless approved with --interactive (0 disables)"`
+	MaxBaseLag               int           `long:"max-base-lag" value-name:"N" description:"how many commits the base branch may be behind its upstream before --stale-base applies"`
+	StaleBase                string        `long:"stale-base" choice:"warn" choice:"abort" choice:"ignore" default:"warn" description:"what to do when the base branch is more than --max-base-lag commits behind its upstream, since analysis against a stale base misses merges and can match old history"`
+	DryRun                   bool          `long:"dry-run" description:"analyze and report what would be deleted, with the scores and matched commits, without deleting anything"`
+	IgnorePaths              []string      `long:"ignore-paths" value-name:"GLOB" default:"go.sum" default:"package-lock.json" default:"yarn.lock" default:"pnpm-lock.yaml" default:"Cargo.lock" default:"poetry.lock" default:"Gemfile.lock" default:"composer.lock" description:"leave files matching this glob out of the diffs compared, since lockfiles are regenerated when merging; a glob without a slash matches file names in any directory; may be repeated, replacing the default lockfiles"`
+	Protect                  []string      `long:"protect" value-name:"GLOB" description:"never analyze or delete branches with this name or matching this glob (e.g. release/*), however merged they look; may be repeated"`
+	SharedCache              bool          `long:"shared-cache" description:"keep the commit cache (see warm-cache) in $XDG_CACHE_HOME, shared by every clone of the same remote repository"`
+	Remote                   string        `long:"remote" optional:"yes" optional-value:"origin" value-name:"REMOTE" description:"also delete the deleted branches from this remote (default: origin), where they still point at commits the local branch had; with report and gitlab-review, analyze the branches of this remote instead of the local ones"`
+	ByAuthor                 bool          `long:"by-author" description:"with report, list the merged branches by the author of their tip, with how many each has and the oldest"`
+	Action                   bool          `long:"action" description:"run as a GitHub Action: options are also read from the INPUT_<OPTION> variables of the action's inputs, and the counts and report path are written to $GITHUB_OUTPUT, with a summary in $GITHUB_STEP_SUMMARY"`
+	ExpiryLabel              string        `long:"expiry-label" optional:"yes" optional-value:"stale-branch" value-name:"LABEL" description:"with --remote, announce each deletion by labelling the branch's merged pull request (default: stale-branch), and only delete the branch once --expiry-days passed with the label still on it; removing the label keeps the branch"`
+	ExpiryDays               int           `long:"expiry-days" default:"7" description:"with --expiry-label, how many days authors have to object before a branch is deleted"`
+	Fetch                    string        `long:"fetch" optional:"yes" optional-value:"origin" value-name:"REMOTE" description:"run git fetch --prune on this remote (default: origin) before analyzing, so the base and the upstreams of branches are current"`
+	ArchiveRename            bool          `long:"archive-rename" description:"rename branches to archive/<date>/<branch> instead of deleting them, so they are easy to find, and to delete later with purge-archive"`
+	Archive                  string        `long:"archive" optional:"yes" optional-value:"ref" choice:"ref" choice:"tag" description:"keep the tip of each deleted branch in refs/archive/<branch>/<tip> (ref, the default), or in an archive/<date>/<branch> tag (tag), so its commits are kept for good"`
 }
 
 func deleteBranch(branchName string) error {
-	fmt.Printf("deleting branch %s\n", branchName)
-	cmd := exec.Command("git", "branch", "-D", branchName)
-	return cmd.Run()
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	infof(T("deleting branch %s\n"), branchName)
+	args := []string{"git", "branch", "-D", "--", branchName}
+	if refNamespace != "" {
+		// git branch only works outside of namespaces
+		args = []string{"git", "update-ref", "-d", branchRef(branchName)}
+	}
+	_, stderr, err := runCommandWithStderr(args...)
+	if err != nil && stderr != "" {
+		// git's first line says what went wrong, the rest is advice
+		return fmt.Errorf("%s", strings.TrimPrefix(strings.SplitN(stderr, "\n", 2)[0], "error: "))
+	}
+	return err
 }
 
 func main() {
@@ -316,72 +665,300 @@ func main() {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		os.Exit(1)
 	}
-	_, err = p.ParseArgs(os.Args[1:])
+	configArgs, err := getConfigFileArgs(p)
 	if err != nil {
-		p.WriteHelp(os.Stderr)
-		os.Exit(1)
+		dieWithCode(exitUsage, T("invalid config file %v\n"), err)
 	}
-
-	branches, err := getBranches()
-	if err != nil {
-		die("failed to get branches: %v\n", err)
+	if isActionMode(os.Args[1:]) {
+		inputArgs, err := getActionInputArgs(p)
+		if err != nil {
+			dieWithCode(exitUsage, T("invalid action inputs: %v\n"), err)
+		}
+		configArgs = append(configArgs, inputArgs...)
 	}
-
-	currentBranch, err := getCurrentBranch()
+	configArgs, cmdArgs := negateConfigFlags(p, configArgs, os.Args[1:])
+	args, err := p.ParseArgs(append(configArgs, cmdArgs...))
 	if err != nil {
-		die("failed to get current branch: %v\n", err)
-	}
-
-	switch currentBranch {
-	case "main", "master", "trunk":
-		break
-	default:
-		die("current branch is %s; expected main, master, or trunk", currentBranch)
+		p.WriteHelp(os.Stderr)
+		os.Exit(exitUsage)
 	}
-
-	for _, branch := range branches {
-		if branch == currentBranch {
-			continue // dont try to delete the current branch (e.g. main)
+	if len(args) > 0 && (args[0] == "analyze" || args[0] == "report" || args[0] == "gitlab-review" || args[0] == "queue" || args[0] == "resolve") {
+		// editor integrations pass their options after the command, e.g. analyze --output=json --branch foo,
+		// and so do people, e.g. resolve --dry-run
+		rest, err := p.ParseArgs(args[1:])
+		if err != nil {
+			p.WriteHelp(os.Stderr)
+			os.Exit(exitUsage)
 		}
-
-		potentialMerged, err := findMerged(currentBranch, branch)
+		args = append(args[:1], rest...)
+	}
+	if progOpts.Schema {
+		os.Stdout.Write(reportSchema)
+		return
+	}
+	setLanguage(progOpts.Lang)
+	logJSON = progOpts.LogFormat == "json"
+	logVerbose = progOpts.Verbose
+	if progOpts.MaxProcs == 0 {
+		progOpts.MaxProcs = runtime.NumCPU()
+	}
+	setMaxProcs(progOpts.MaxProcs)
+	maxLoad = progOpts.MaxLoad
+	iAmMaintainer = progOpts.IAmMaintainer
+	reportJSON = progOpts.Output == "json"
+	reportTable = progOpts.Output == "table"
+	noTruncate = progOpts.NoTruncate
+	if progOpts.Format != "" {
+		if progOpts.Output != "text" {
+			dieWithCode(exitUsage, T("--format and --output=%s can not be combined\n"), progOpts.Output)
+		}
+		reportTemplate, err = parseReportTemplate(progOpts.Format)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "ignoring %s due to: %s\n", branch, err)
+			dieWithCode(exitUsage, T("invalid --format: %v\n"), err)
 		}
-		if potentialMerged == nil {
-			continue // likely not merged
+	}
+	stripSubjectEmoji = progOpts.StripEmoji
+	matchWindow = time.Duration(progOpts.MatchWindow)
+	allowPredatingMatches = progOpts.AllowPredatingMatches
+	minStatScore = progOpts.MinStatScore
+	diffEngine = progOpts.Engine
+	ignorePaths = progOpts.IgnorePaths
+	protectPatterns = progOpts.Protect
+	sharedCache = progOpts.SharedCache
+	if !progOpts.UseReplaceObjects {
+		// inherited by every git subprocess
+		os.Setenv("GIT_NO_REPLACE_OBJECTS", "1")
+	}
+
+	if progOpts.Stdio {
+		if len(args) > 0 {
+			dieWithCode(exitUsage, T("--stdio can't be combined with a command\n"))
 		}
-
-		if potentialMerged.Merged {
-			fmt.Printf("%s was cleanly merged under %s\n", branch, potentialMerged.MergedSha)
-			if err := deleteBranch(branch); err != nil {
-				die("failed to delete branch %s: %v", branch, err)
-			}
-			fmt.Printf("\n")
-			continue
+		if err := serveStdio(&progOpts, os.Stdin, os.Stdout); err != nil {
+			die(T("failed to serve requests: %v\n"), err)
 		}
+		return
+	}
 
-		if potentialMerged.SubjectScore > progOpts.MinSubjectScore && potentialMerged.DiffScore > progOpts.MinDiffScore {
-			perfectDiffMatch := bool(potentialMerged.DiffScore == 1.0 && potentialMerged.DiffSize > 10)
-
-			if perfectDiffMatch {
-				fmt.Printf("%s was merged under %s (subject score: %f; diff score %f)\n", branch, potentialMerged.MergedSha, potentialMerged.SubjectScore, potentialMerged.DiffScore)
-				if err := deleteBranch(branch); err != nil {
-					die("failed to delete branch %s: %v", branch, err)
+	if len(args) > 0 {
+		switch args[0] {
+		case "stats":
+			checkRepository(false)
+			if err := showStats(); err != nil {
+				die(T("failed to show stats: %v\n"), err)
+			}
+		case "released-in":
+			checkRepository(false)
+			if len(args) != 2 {
+				dieWithCode(exitUsage, T("usage: %s released-in <tag>\n"), progName)
+			}
+			if err := showReleasedIn(args[1], &progOpts); err != nil {
+				die(T("failed to list branches released in %s: %v\n"), args[1], err)
+			}
+		case "queue", "resolve":
+			if len(args) > 2 {
+				dieWithCode(exitUsage, T("usage: %s %s [queue.json]\n"), progName, args[0])
+			}
+			var path string
+			if len(args) == 2 {
+				path = args[1]
+			}
+			rs := newRunState(&progOpts)
+			if args[0] == "queue" {
+				if err := queueCandidates(path, rs.analyze(), rs.deleteDisabled); err != nil {
+					die(T("failed to queue branches: %v\n"), err)
 				}
-				fmt.Printf("\n")
-				continue
+			} else if err := resolveQueue(rs, path); err != nil {
+				die(T("failed to resolve queue: %v\n"), err)
 			}
-
-			// Code Diff is not perfect, don't auto-delete anything below
-
-			fmt.Printf("%s was **potentially** merged under %s (subject score: %f; diff score %f)\n", branch, potentialMerged.MergedSha, potentialMerged.SubjectScore, potentialMerged.DiffScore)
-			if potentialMerged.NumCommits > 1 {
-				fmt.Printf("WARNING: %s contains %d commits, comparing combined diffs instead (and ommitting commit message)\n", branch, potentialMerged.NumCommits)
+		case "fork-sync":
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s fork-sync\n"), progName)
+			}
+			if progOpts.Base != "" {
+				dieWithCode(exitUsage, T("fork-sync always compares against the upstream remote; --base can't be used\n"))
+			}
+			if progOpts.Remote != "" {
+				dieWithCode(exitUsage, T("fork-sync always deletes from the fork; --remote can't be used\n"))
+			}
+			if err := forkSync(newRunState(&progOpts), progOpts.SyncBase); err != nil {
+				die(T("fork-sync failed: %v\n"), err)
+			}
+		case "warm-cache":
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s warm-cache\n"), progName)
+			}
+			if progOpts.UseReplaceObjects {
+				dieWithCode(exitUsage, T("the cache can't be warmed with --use-replace-objects\n"))
+			}
+			if err := warmCache(newRunState(&progOpts)); err != nil {
+				die(T("failed to warm the cache: %v\n"), err)
 			}
-			fmt.Printf("%s\n", potentialMerged.DiffCmd)
-			fmt.Printf("git branch -D %s\n", branch)
-			fmt.Printf("\n")
+		case "purge-archive":
+			checkRepository(false)
+			days := 30
+			if len(args) == 2 {
+				days, err = strconv.Atoi(args[1])
+			}
+			if len(args) > 2 || err != nil || days < 0 {
+				dieWithCode(exitUsage, T("usage: %s purge-archive [<days>]\n"), progName)
+			}
+			if err := purgeArchive(days); err != nil {
+				die(T("failed to purge the archive: %v\n"), err)
+			}
+		case "clean-config":
+			checkRepository(false)
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s clean-config\n"), progName)
+			}
+			removed, err := removeOrphanedBranchConfig(nil)
+			for _, name := range removed {
+				infof(T("removed leftover config of deleted branch %s\n"), name)
+			}
+			if err != nil {
+				die(T("failed to clean config: %v\n"), err)
+			}
+		case "rollback":
+			checkRepository(false)
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s rollback\n"), progName)
+			}
+			if err := rollback(); err != nil {
+				die(T("failed to roll back: %v\n"), err)
+			}
+		case "restore":
+			checkRepository(false)
+			if err := restore(args[1:], os.Stdin); err != nil {
+				die(T("failed to restore: %v\n"), err)
+			}
+		case "graph":
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s graph\n"), progName)
+			}
+			if err := showGraph(os.Stdout, newRunState(&progOpts), progOpts.GraphFormat); err != nil {
+				die(T("failed to graph branches: %v\n"), err)
+			}
+		case "heatmap":
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s heatmap\n"), progName)
+			}
+			if progOpts.HeatmapDepth < 1 {
+				dieWithCode(exitUsage, T("--heatmap-depth must be at least 1\n"))
+			}
+			if err := showHeatmap(newRunState(&progOpts), progOpts.HeatmapDepth); err != nil {
+				die(T("failed to build the heatmap: %v\n"), err)
+			}
+		case "selftest":
+			// hidden: for checking a git version or platform before trusting deletions
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s selftest\n"), progName)
+			}
+			if err := selftest(&progOpts); err != nil {
+				die(T("selftest failed: %v\n"), err)
+			}
+		case "analyze":
+			if len(args) != 1 || progOpts.Branch == "" {
+				dieWithCode(exitUsage, T("usage: %s analyze --branch <name>\n"), progName)
+			}
+			rs := newRunState(&progOpts)
+			report, err := rs.analyzeOnly(progOpts.Branch)
+			if err != nil {
+				dieWithCode(exitUsage, T("failed to analyze %s: %v\n"), progOpts.Branch, err)
+			}
+			switch {
+			case reportJSON:
+				err = writeJSON(os.Stdout, report)
+			case reportTable:
+				err = writeReportTable(report.Branches)
+			case reportTemplate != nil:
+				err = writeFormatted(os.Stdout, report.Branches)
+			default:
+				for _, b := range report.Branches {
+					reportf("%s: %s\n", b.Branch, b.Classification)
+				}
+			}
+			if err != nil {
+				die(T("failed to write report: %v\n"), err)
+			}
+		case "report":
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s report [--remote[=<remote>]] [--by-author]\n"), progName)
+			}
+			rs := newRunState(&progOpts)
+			if progOpts.Remote != "" {
+				if err := rs.useRemoteBranches(progOpts.Remote); err != nil {
+					die(T("failed to list the branches of %s: %v\n"), progOpts.Remote, err)
+				}
+			}
+			if err := showLandedReport(rs.analyze(), progOpts.ByAuthor); err != nil {
+				die(T("failed to write report: %v\n"), err)
+			}
+		case "gitlab-review":
+			if len(args) != 1 {
+				dieWithCode(exitUsage, T("usage: %s gitlab-review [--remote=<remote>] [--dry-run]\n"), progName)
+			}
+			remote := progOpts.Remote
+			if remote == "" {
+				remote = "origin"
+			}
+			if err := gitLabReview(newRunState(&progOpts), remote); err != nil {
+				die(T("failed to review the branches of %s: %v\n"), remote, err)
+			}
+		case "show":
+			if len(args) != 2 {
+				dieWithCode(exitUsage, T("usage: %s show <branch>\n"), progName)
+			}
+			if err := showEvidence(newRunState(&progOpts), args[1]); err != nil {
+				die(T("failed to show %s: %v\n"), args[1], err)
+			}
+		case "diff-report":
+			if len(args) != 3 {
+				dieWithCode(exitUsage, T("usage: %s diff-report <old.json> <new.json>\n"), progName)
+			}
+			if err := diffReports(args[1], args[2]); err != nil {
+				die(T("failed to diff reports: %v\n"), err)
+			}
+		default:
+			dieWithCode(exitUsage, T("unknown command: %s\n"), args[0])
+		}
+		return
+	}
+
+	if progOpts.Action && progOpts.Interactive {
+		dieWithCode(exitUsage, T("--action can't be combined with --interactive\n"))
+	}
+	rs := newRunState(&progOpts)
+	report := rs.analyze()
+	rs.cleanup(report)
+	if progOpts.Action {
+		if err := writeActionOutputs(report, rs.stats); err != nil {
+			die(T("failed to write the action's outputs: %v\n"), err)
+		}
+	}
+	complete := rs.stats.Errors == 0 && rs.stats.DeleteFailures == 0
+	if progOpts.RecurseSubmodules && !cleanupSubmodules(&progOpts, report) {
+		complete = false
+	}
+	if reportJSON {
+		if err := writeJSON(os.Stdout, report); err != nil {
+			die(T("failed to write report: %v\n"), err)
 		}
 	}
+	if reportTemplate != nil || reportTable {
+		branches := report.Branches
+		for _, s := range report.Submodules {
+			branches = append(branches, s.Branches...)
+		}
+		if reportTable {
+			err = writeReportTable(branches)
+		} else {
+			err = writeFormatted(os.Stdout, branches)
+		}
+		if err != nil {
+			die(T("failed to write report: %v\n"), err)
+		}
+	}
+	if !complete {
+		os.Exit(exitIncomplete)
+	}
 }
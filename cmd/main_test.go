@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// runMainEnv makes the test binary run main instead of the tests, so tests can
+// run the command line against a scratch repo (see runCLI)
+const runMainEnv = "GIT_BRANCH_CLEANUP_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testEnv is the environment of the commands tests run, isolated from the
+// user's config, so every run sees the same repo
+func testEnv(home string) []string {
+	env := []string{}
+	for _, kv := range os.Environ() {
+		switch name, _, _ := strings.Cut(kv, "="); {
+		case strings.HasPrefix(name, "GIT_"), name == "HOME", name == "XDG_CONFIG_HOME":
+		default:
+			env = append(env, kv)
+		}
+	}
+	return append(env,
+		"HOME="+home,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+}
+
+// testRepo is a scratch repo, cloned from a bare origin in the same directory
+type testRepo struct {
+	t      *testing.T
+	dir    string // the clone, where commands run
+	origin string // the bare repo origin points at
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	root := t.TempDir()
+	r := &testRepo{t: t, dir: filepath.Join(root, "clone"), origin: filepath.Join(root, "origin.git")}
+	r.gitIn(root, "init", "--quiet", "--bare", "--initial-branch=main", r.origin)
+	r.gitIn(root, "init", "--quiet", "--initial-branch=main", r.dir)
+	r.git("remote", "add", "origin", r.origin)
+	for i := 1; i <= 3; i++ {
+		lines := []string{}
+		for n := 1; n <= 40; n++ {
+			lines = append(lines, fmt.Sprintf("file %d line %d", i, n))
+		}
+		r.write(fmt.Sprintf("f%d.txt", i), strings.Join(lines, "\n")+"\n")
+	}
+	r.git("add", ".")
+	r.git("commit", "--quiet", "-m", "initial commit")
+	return r
+}
+
+func (r *testRepo) gitIn(dir string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = testEnv(filepath.Dir(r.dir))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	return r.gitIn(r.dir, args...)
+}
+
+func (r *testRepo) write(file, contents string) {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, file), []byte(contents), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit changes line n (counting from 1) of an existing file, and commits it
+func (r *testRepo) commit(subject, file string, n int, text string) {
+	r.t.Helper()
+	contents, err := os.ReadFile(filepath.Join(r.dir, file))
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	lines := strings.Split(string(contents), "\n")
+	lines[n-1] = text
+	r.write(file, strings.Join(lines, "\n"))
+	r.git("commit", "--quiet", "-am", subject)
+}
+
+func (r *testRepo) hasBranch(branch string) bool {
+	r.t.Helper()
+	return exec.Command("git", "-C", r.dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Run() == nil
+}
+
+func (r *testRepo) originHasBranch(branch string) bool {
+	r.t.Helper()
+	return exec.Command("git", "-C", r.origin, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Run() == nil
+}
+
+// enter runs the rest of the test in the repo, with the environment of
+// testEnv, since the git helpers run in the working directory
+func (r *testRepo) enter() {
+	r.t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, "GIT_") {
+			r.t.Setenv(name, "") // restored once the test is done
+			os.Unsetenv(name)
+		}
+	}
+	for _, kv := range testEnv(filepath.Dir(r.dir)) {
+		name, value, _ := strings.Cut(kv, "=")
+		r.t.Setenv(name, value)
+	}
+	if err := os.Chdir(r.dir); err != nil {
+		r.t.Fatal(err)
+	}
+	allowedOperation = nil // read from this repo's config
+	r.t.Cleanup(func() {
+		os.Chdir(wd)
+		allowedOperation = nil
+	})
+}
+
+// runCLI runs the command line in the repo, returning stdout, and stderr
+func (r *testRepo) runCLI(args ...string) (string, string, error) {
+	r.t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(testEnv(filepath.Dir(r.dir)), runMainEnv+"=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// refNames are branch names as they turn up in real repos, including the ones
+// git-svn and git-remote-hg imports create, and some git itself rejects but
+// which the analysis must still handle safely when given them
+var refNames = []string{
+	"main",
+	"feature/foo",
+	"feature/foo/bar",
+	"user/jdoe/fix-1234",
+	"-leading-dash",
+	"--",
+	"with space",
+	"tab\there",
+	"quote'd",
+	`double"quote`,
+	"$(rm -rf /)",
+	"`id`",
+	"semi;colon",
+	"glob*?[ab]",
+	"back\\slash",
+	"fix/ünïcødé",
+	"日本語/ブランチ",
+	"emoji-🚀",
+	"tags/1.0",           // git-svn tags
+	"trunk@1234",         // git-svn peg revisions
+	"svn/branches/rel-1", // git-svn with --prefix=svn/
+	"branches/default",   // git-remote-hg named branches
+	"bookmarks/feature",  // git-remote-hg bookmarks
+	"hg/default@2",
+}
+
+func TestBranchRef(t *testing.T) {
+	for _, name := range refNames {
+		ref := branchRef(name)
+		if !strings.HasPrefix(ref, branchPrefix) {
+			t.Errorf("branchRef(%q) = %q, which is outside %s", name, ref, branchPrefix)
+		}
+		// getBranches recovers names this way
+		if got := strings.TrimPrefix(ref, branchPrefix); got != name {
+			t.Errorf("branchRef(%q) = %q, which names %q", name, ref, got)
+		}
+	}
+}
+
+func TestRemoteBranchName(t *testing.T) {
+	for _, name := range refNames {
+		for _, remote := range []string{"origin", "svn", "hg-import"} {
+			ref := remotePrefix + remote + "/" + name
+			if got := remoteBranchName(ref); got != name {
+				t.Errorf("remoteBranchName(%q) = %q, expected %q", ref, got, name)
+			}
+		}
+	}
+	for _, ref := range []string{remotePrefix + "origin", "origin", ""} {
+		if got := remoteBranchName(ref); got != "" {
+			t.Errorf("remoteBranchName(%q) = %q, expected no branch", ref, got)
+		}
+	}
+}
+
+func FuzzRemoteBranchName(f *testing.F) {
+	for _, name := range refNames {
+		f.Add("origin", name)
+	}
+	f.Fuzz(func(t *testing.T, remote, name string) {
+		if remote == "" || strings.Contains(remote, "/") {
+			t.Skip("remote names have no slashes")
+		}
+		ref := remotePrefix + remote + "/" + name
+		if got := remoteBranchName(ref); got != name {
+			t.Errorf("remoteBranchName(%q) = %q, expected %q", ref, got, name)
+		}
+	})
+}
+
+// FuzzShellQuote checks that the shell reads a quoted string back as the
+// string itself, so the suggested commands act on the right branch
+func FuzzShellQuote(f *testing.F) {
+	for _, name := range refNames {
+		f.Add(name)
+	}
+	f.Add("")
+	f.Add("'")
+	f.Add("''")
+	f.Add("\n")
+	f.Fuzz(func(t *testing.T, s string) {
+		if strings.ContainsRune(s, 0) {
+			t.Skip("shell arguments can't contain NUL")
+		}
+		quoted := shellQuote(s)
+		out, err := exec.Command("sh", "-c", "printf %s "+quoted).Output()
+		if err != nil {
+			t.Fatalf("sh -c 'printf %%s %s': %v", quoted, err)
+		}
+		if string(out) != s {
+			t.Errorf("shellQuote(%q) = %s, which the shell reads as %q", s, quoted, out)
+		}
+	})
+}
+
+// TestReportJSONRoundTrip checks that branch names survive the JSON report,
+// and the receipts restore parses from a text report
+func TestReportJSONRoundTrip(t *testing.T) {
+	report := &Report{SchemaVersion: reportSchemaVersion, Base: "main"}
+	var receipts bytes.Buffer
+	for _, name := range refNames {
+		receipt := &Receipt{Branch: name, TipSha: "0123456789abcdef0123456789abcdef01234567", Reason: "merged"}
+		receipt.Restore = "git branch " + shellQuote(name) + " " + receipt.TipSha
+		report.Branches = append(report.Branches, &BranchReport{Branch: name, Classification: classMerged, Deleted: true, Receipt: receipt})
+
+		line, err := json.Marshal(receipt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts.WriteString(receiptPrefix + string(line) + "\n")
+	}
+
+	contents, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(contents, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Branches) != len(refNames) {
+		t.Fatalf("decoded %d branches, expected %d", len(decoded.Branches), len(refNames))
+	}
+	for i, name := range refNames {
+		b := decoded.Branches[i]
+		if b.Branch != name || b.Receipt == nil || b.Receipt.Branch != name {
+			t.Errorf("%q was decoded as %q", name, b.Branch)
+		}
+	}
+
+	parsed, err := parseReceipts(&receipts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(refNames) {
+		t.Fatalf("parsed %d receipts, expected %d", len(parsed), len(refNames))
+	}
+	for i, name := range refNames {
+		if parsed[i].Branch != name {
+			t.Errorf("the receipt of %q was parsed as %q", name, parsed[i].Branch)
+		}
+	}
+}
+
+func FuzzReportJSON(f *testing.F) {
+	for _, name := range refNames {
+		f.Add(name)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if !utf8.ValidString(name) {
+			t.Skip("JSON strings are UTF-8")
+		}
+		contents, err := json.Marshal(&BranchReport{Branch: name, Classification: classMerged})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded BranchReport
+		if err := json.Unmarshal(contents, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Branch != name {
+			t.Errorf("%q was decoded as %q", name, decoded.Branch)
+		}
+	})
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// catalogs maps a language to translations of user-facing format strings.
+// Strings missing from a catalog are printed untranslated (in English).
+var catalogs = map[string]map[string]string{
+	"de": {
+		"%s was cleanly merged under %s\n":                                                                   "%s wurde sauber unter %s zusammengeführt\n",
+		"%s was merged under %s (subject score: %f; diff score %f)\n":                                        "%s wurde unter %s zusammengeführt (Betreff-Wert: %f; Diff-Wert %f)\n",
+		"%s was **potentially** merged under %s (subject score: %f; diff score %f)\n":                        "%s wurde **möglicherweise** unter %s zusammengeführt (Betreff-Wert: %f; Diff-Wert %f)\n",
+		"WARNING: %s contains %d commits, comparing combined diffs instead (and ommitting commit message)\n": "WARNUNG: %s enthält %d Commits, stattdessen werden die kombinierten Diffs verglichen (ohne Commit-Nachricht)\n",
+		"deleting branch %s\n":                                         "lösche Branch %s\n",
+		"forgetting jj bookmark %s\n":                                  "vergesse jj-Bookmark %s\n",
+		"skipping %s: tracked by %s\n":                                 "überspringe %s: wird von %s verwaltet\n",
+		"skipping %s: active jj bookmark\n":                            "überspringe %s: aktives jj-Bookmark\n",
+		"ignoring %s due to: %s\n":                                     "ignoriere %s wegen: %s\n",
+		"failed to get branches: %v\n":                                 "Branches konnten nicht ermittelt werden: %v\n",
+		"failed to get current branch: %v\n":                           "aktueller Branch konnte nicht ermittelt werden: %v\n",
+		"current branch is %s; expected main, master, or trunk":        "aktueller Branch ist %s; erwartet wurde main, master oder trunk",
+		"failed to detect stacked-diff tools: %v\n":                    "Stacked-Diff-Werkzeuge konnten nicht erkannt werden: %v\n",
+		"failed to list jj bookmarks: %v\n":                            "jj-Bookmarks konnten nicht aufgelistet werden: %v\n",
+		"failed to show stats: %v\n":                                   "Statistiken konnten nicht angezeigt werden: %v\n",
+		"failed to write stats: %v\n":                                  "Statistiken konnten nicht geschrieben werden: %v\n",
+		"unknown command: %s\n":                                        "unbekannter Befehl: %s\n",
+		"%s %5d branches %-*s (deleted %d; %.1fs; cache hits %d/%d)\n": "%s %5d Branches %-*s (gelöscht %d; %.1fs; Cache-Treffer %d/%d)\n",
+		"failed to sort branches: %v\n":                                "Branches konnten nicht sortiert werden: %v\n",
+		"failed to write report: %v\n":                                 "Bericht konnte nicht geschrieben werden: %v\n",
+		"failed to diff reports: %v\n":                                 "Berichte konnten nicht verglichen werden: %v\n",
+		"usage: %s diff-report <old.json> <new.json>\n":                "Verwendung: %s diff-report <alt.json> <neu.json>\n",
+		"new candidates:\n":                                            "neue Kandidaten:\n",
+		"deleted:\n":                                                   "gelöscht:\n",
+		"changed classification:\n":                                    "geänderte Einstufung:\n",
+		"failed to determine protected branches: %v\n":                 "geschützte Branches konnten nicht ermittelt werden: %v\n",
+		"skipping %s: protected (%s)\n":                                "überspringe %s: geschützt (%s)\n",
+		"failed to list worktrees: %v\n":                               "Worktrees konnten nicht aufgelistet werden: %v\n",
+		"%s is checked out elsewhere (%s)\n":                           "%s wird anderswo verwendet (%s)\n",
+		"%s points at the tip of %s; keeping it (see --delete-tracking-duplicates)\n": "%s zeigt auf die Spitze von %s; wird behalten (siehe --delete-tracking-duplicates)\n",
+		"%s points at the tip of %s\n":    "%s zeigt auf die Spitze von %s\n",
+		"failed to sample branches: %v\n": "Stichprobe konnte nicht gezogen werden: %v\n",
+		"would delete branch %s\n":        "würde Branch %s löschen\n",
+		"sampled %d of %d branches: %d candidates, %d deletable (estimated %d candidates, %d deletable across all branches)\n": "Stichprobe von %d aus %d Branches: %d Kandidaten, %d löschbar (geschätzt %d Kandidaten, %d löschbar über alle Branches)\n",
+		"failed to group branches: %v\n":                                                                           "Branches konnten nicht gruppiert werden: %v\n",
+		"== %s: %d branches, %d candidates, %d bytes of diffs ==\n\n":                                              "== %s: %d Branches, %d Kandidaten, %d Bytes an Diffs ==\n\n",
+		"failed to load progress: %v\n":                                                                            "Fortschritt konnte nicht geladen werden: %v\n",
+		"failed to save progress: %v\n":                                                                            "Fortschritt konnte nicht gespeichert werden: %v\n",
+		"stopped after analyzing %d of %d branches; the next run resumes after %s\n":                               "nach %d von %d Branches angehalten; der nächste Lauf setzt nach %s fort\n",
+		"%s has the same tree as %s\n":                                                                             "%s hat denselben Tree wie %s\n",
+		"%s was rebased onto %s, which has not been merged\n":                                                      "%s wurde auf %s rebased, das noch nicht zusammengeführt wurde\n",
+		"failed to delete branch %s: %v\n":                                                                         "Branch %s konnte nicht gelöscht werden: %v\n",
+		"%s only contains empty commits; keeping it (see --delete-empty)\n":                                        "%s enthält nur leere Commits; wird behalten (siehe --delete-empty)\n",
+		"%s only contains empty commits\n":                                                                         "%s enthält nur leere Commits\n",
+		"failed to resolve base %s: %v\n":                                                                          "Basis %s konnte nicht aufgelöst werden: %v\n",
+		"usage: %s released-in <tag>\n":                                                                            "Verwendung: %s released-in <Tag>\n",
+		"failed to list branches released in %s: %v\n":                                                             "in %s veröffentlichte Branches konnten nicht aufgelistet werden: %v\n",
+		"%s was released in %s as %s\n":                                                                            "%s wurde in %s als %s veröffentlicht\n",
+		"%s was released in %s\n":                                                                                  "%s wurde in %s veröffentlicht\n",
+		"git was not found in PATH; install git or add it to PATH\n":                                               "git wurde nicht im PATH gefunden; installieren Sie git oder ergänzen Sie den PATH\n",
+		"not a git repository: run this from inside a git checkout (%s)\n":                                         "kein git-Repository: bitte innerhalb eines git-Checkouts ausführen (%s)\n",
+		"HEAD does not point at a commit: the repository is empty or HEAD is corrupt (check .git/HEAD)\n":          "HEAD zeigt auf keinen Commit: das Repository ist leer oder HEAD ist beschädigt (siehe .git/HEAD)\n",
+		"found broken branch refs; repair or delete them (e.g. git update-ref -d <ref>) and run git fsck:\n  %s\n": "beschädigte Branch-Refs gefunden; reparieren oder löschen Sie sie (z.B. git update-ref -d <Ref>) und führen Sie git fsck aus:\n  %s\n",
+		"failed to audit repository: %v\n":                                                                         "Repository konnte nicht geprüft werden: %v\n",
+		"WARNING: analysis may be unreliable: %s\n":                                                                "WARNUNG: die Analyse ist möglicherweise unzuverlässig: %s\n",
+		"failed to export evidence: %v\n":                                                                          "Belege konnten nicht exportiert werden: %v\n",
+		"queued %d branches for review in %s\n":                                                                    "%d Branches zur Überprüfung in %s eingereiht\n",
+		"%s no longer exists\n":                                                                                    "%s existiert nicht mehr\n",
+		"%s has changed since it was reviewed; keeping it\n":                                                       "%s hat sich seit der Überprüfung geändert; wird behalten\n",
+		"%s will no longer be suggested for deletion\n":                                                            "%s wird nicht mehr zum Löschen vorgeschlagen\n",
+		"skipping %s: rejected in review\n":                                                                        "überspringe %s: bei der Überprüfung abgelehnt\n",
+		"failed to load review rejections: %v\n":                                                                   "Ablehnungen konnten nicht geladen werden: %v\n",
+		"usage: %s %s [queue.json]\n":                                                                              "Verwendung: %s %s [queue.json]\n",
+		"failed to queue branches: %v\n":                                                                           "Branches konnten nicht eingereiht werden: %v\n",
+		"failed to resolve queue: %v\n":                                                                            "Warteschlange konnte nicht abgearbeitet werden: %v\n",
+		"failed to read shared review decisions: %v\n":                                                             "gemeinsame Entscheidungen konnten nicht gelesen werden: %v\n",
+		"failed to list remotes: %v\n":                                                                             "Remotes konnten nicht aufgelistet werden: %v\n",
+		"remote %s does not exist\n":                                                                               "Remote %s existiert nicht\n",
+		"failed to resolve the default branch of %s: %v\n":                                                         "Standardbranch von %s konnte nicht ermittelt werden: %v\n",
+		"comparing against %s, since %s is a fork of %s\n":                                                         "vergleiche mit %s, da %s ein Fork von %s ist\n",
+		"to delete it from %s too: git push --delete %s %s\n":                                                      "um ihn auch aus %s zu löschen: git push --delete %s %s\n",
+		"failed to get branch upstreams: %v\n":                                                                     "Upstreams der Branches konnten nicht ermittelt werden: %v\n",
+		"deleting %s from %s\n":                                                                                    "lösche %s von %s\n",
+		"fast-forwarding %s to %s\n":                                                                               "spule %s auf %s vor\n",
+		"usage: %s fork-sync\n":                                                                                    "Verwendung: %s fork-sync\n",
+		"fork-sync always compares against the upstream remote; --base can't be used\n":                            "fork-sync vergleicht immer mit dem Upstream-Remote; --base kann nicht verwendet werden\n",
+		"fork-sync failed: %v\n":                                                                                   "fork-sync fehlgeschlagen: %v\n",
+		"%s may have been merged: its %s\n":                                                                        "%s wurde möglicherweise gemergt: sein %s\n",
+		"cached %d new commits (%d in total)\n":                                                                    "%d neue Commits zwischengespeichert (%d insgesamt)\n",
+		"usage: %s warm-cache\n":                                                                                   "Verwendung: %s warm-cache\n",
+		"the cache can't be warmed with --use-replace-objects\n":                                                   "der Cache kann mit --use-replace-objects nicht vorberechnet werden\n",
+		"failed to warm the cache: %v\n":                                                                           "Cache konnte nicht vorberechnet werden: %v\n",
+		"ignoring the commit cache: %v\n":                                                                          "ignoriere den Commit-Cache: %v\n",
+		"the best match for %s, %s, is older than --match-window; ignoring it\n":                                   "der beste Treffer für %s, %s, ist älter als --match-window; wird ignoriert\n",
+		"failed to detect duplicate branches: %v\n":                                                                "doppelte Branches konnten nicht erkannt werden: %v\n",
+		"%s is a duplicate: %s (see --delete-duplicates)\n":                                                        "%s ist ein Duplikat: %s (siehe --delete-duplicates)\n",
+		"%s is a duplicate: %s\n":                                                                                  "%s ist ein Duplikat: %s\n",
+		"failed to detect backup branches: %v\n":                                                                   "Backup-Branches konnten nicht erkannt werden: %v\n",
+		"%s is a backup copy, %s (see --clean-backups)\n":                                                          "%s ist eine Sicherungskopie, %s (siehe --clean-backups)\n",
+		"%s is a backup copy, %s\n":                                                                                "%s ist eine Sicherungskopie, %s\n",
+		"failed to remove the config of deleted branches: %v\n":                                                    "Konfiguration gelöschter Branches konnte nicht entfernt werden: %v\n",
+		"removed leftover config of deleted branch %s\n":                                                           "übrig gebliebene Konfiguration des gelöschten Branches %s entfernt\n",
+		"usage: %s clean-config\n":                                                                                 "Verwendung: %s clean-config\n",
+		"failed to clean config: %v\n":                                                                             "Konfiguration konnte nicht bereinigt werden: %v\n",
+		"not restoring %s: it has been recreated at %s\n":                                                          "stelle %s nicht wieder her: er wurde bei %s neu erstellt\n",
+		"restored %s at %s\n":                                                                                      "%s bei %s wiederhergestellt\n",
+		"about to delete:\n":                                                                                       "wird gelöscht:\n",
+		"delete these %d branches? [y/N] ":                                                                         "diese %d Branches löschen? [y/N] ",
+		"failed to save checkpoint: %v\n":                                                                          "Checkpoint konnte nicht gespeichert werden: %v\n",
+		"to restore them all, run the rollback command\n":                                                          "führe den Befehl rollback aus, um alle wiederherzustellen\n",
+		"usage: %s rollback\n":                                                                                     "Verwendung: %s rollback\n",
+		"failed to roll back: %v\n":                                                                                "Zurücksetzen fehlgeschlagen: %v\n",
+		"usage: %s graph\n":                                                                                        "Verwendung: %s graph\n",
+		"failed to graph branches: %v\n":                                                                           "Branch-Graph konnte nicht erstellt werden: %v\n",
+		"usage: %s heatmap\n":                                                                                      "Verwendung: %s heatmap\n",
+		"--heatmap-depth must be at least 1\n":                                                                     "--heatmap-depth muss mindestens 1 sein\n",
+		"failed to build the heatmap: %v\n":                                                                        "Heatmap konnte nicht erstellt werden: %v\n",
+		"directory":                                                                                                "Verzeichnis",
+		"lines":                                                                                                    "Zeilen",
+		"files":                                                                                                    "Dateien",
+		"branches":                                                                                                 "Branches",
+		"the deleted branches retained %s of objects, which git gc prunes once no reflog (e.g. HEAD's) reaches them any more and they are older than gc.pruneExpire\n": "die gelöschten Branches hielten %s an Objekten, die git gc entfernt, sobald kein Reflog (z. B. der von HEAD) sie mehr erreicht und sie älter als gc.pruneExpire sind\n",
+		"failed to estimate disk usage: %v\n":     "Speicherbedarf konnte nicht geschätzt werden: %v\n",
+		"running git gc --prune=%s\n":             "führe git gc --prune=%s aus\n",
+		"git gc failed: %v\n":                     "git gc fehlgeschlagen: %v\n",
+		"repository objects went from %s to %s\n": "die Repository-Objekte schrumpften von %s auf %s\n",
+		"nothing was freed yet: the deleted branches' objects are still referenced by reflogs, or are newer than --gc-prune\n": "noch nichts freigegeben: die Objekte der gelöschten Branches werden noch von Reflogs referenziert oder sind neuer als --gc-prune\n",
+		"failed to expire reflogs: %v\n":                                                                "Reflogs konnten nicht ablaufen gelassen werden: %v\n",
+		"failed to check which paths %s touches: %v\n":                                                  "konnte nicht prüfen, welche Pfade %s ändert: %v\n",
+		"ignoring CODEOWNERS pattern %s: %v\n":                                                          "ignoriere CODEOWNERS-Muster %s: %v\n",
+		"failed to read CODEOWNERS: %v\n":                                                               "CODEOWNERS konnte nicht gelesen werden: %v\n",
+		"--owned-by needs a CODEOWNERS file, but there is none in %s\n":                                 "--owned-by benötigt eine CODEOWNERS-Datei, aber %s enthält keine\n",
+		"failed to find the owners of %s: %v\n":                                                         "Besitzer von %s konnten nicht ermittelt werden: %v\n",
+		"branch-cleanup.protectCommand %q failed (%v); using its output from the last successful run\n": "branch-cleanup.protectCommand %q fehlgeschlagen (%v); verwende die Ausgabe des letzten erfolgreichen Laufs\n",
+		"failed to read config: %v\n":                                                                   "Konfiguration konnte nicht gelesen werden: %v\n",
+		"%s is too complex to analyze (%s); review it manually\n":                                       "%s ist zu komplex für die Analyse (%s); bitte manuell prüfen\n",
+		"compare each commit with its closest counterpart in %s:\n":                                     "jeden Commit mit seinem ähnlichsten Gegenstück in %s vergleichen:\n",
+		"set \"decision\" to \"%s\", \"%s\", or \"%s\", then run the resolve command\n":                 "setze \"decision\" auf \"%s\", \"%s\" oder \"%s\" und führe dann den Befehl resolve aus\n",
+		"delete %s? [y/n/d/r/q] (d: show the diff, r: never ask again) ":                                "%s löschen? [y/n/d/r/q] (d: Diff anzeigen, r: nie wieder fragen) ",
+		"skipping %s for now\n":                                                                         "%s wird vorerst übersprungen\n",
+		"%d branches will no longer be suggested for deletion\n":                                        "%d Branches werden nicht mehr zum Löschen vorgeschlagen\n",
+		"failed to reject %s: %v\n":                                                                     "Ablehnen von %s fehlgeschlagen: %v\n",
+		"failed to save rejections: %v\n":                                                               "Speichern der Ablehnungen fehlgeschlagen: %v\n",
+		"invalid --scope: %v\n":                                                                         "ungültiger --scope: %v\n",
+		"only analyzing branches which change files under %s/\n":                                        "es werden nur Branches analysiert, die Dateien unter %s/ ändern\n",
+		"failed to analyze %s: %v\n":                                                                    "Analyse von %s fehlgeschlagen: %v\n",
+		"%s could not be analyzed: %s\n":                                                                "%s konnte nicht analysiert werden: %s\n",
+		"failed to delete %d branches:\n":                                                               "%d Branches konnten nicht gelöscht werden:\n",
+		"failed to log the deletion of %s: %v\n":                                                        "Protokollieren der Löschung von %s fehlgeschlagen: %v\n",
+		"pull request #%d: %s (merged %s)\n":                                                            "Pull-Request #%d: %s (gemergt am %s)\n",
+		"failed to find pull requests: %v\n":                                                            "Suche nach Pull-Requests fehlgeschlagen: %v\n",
+		"failed to find the top of the worktree: %v\n":                                                  "Oberstes Verzeichnis des Arbeitsbaums nicht gefunden: %v\n",
+		"failed to list submodules: %v\n":                                                               "Auflisten der Submodule fehlgeschlagen: %v\n",
+		"failed to get the current directory: %v\n":                                                     "Aktuelles Verzeichnis nicht ermittelbar: %v\n",
+		"skipping submodule %s: it has no default branch to compare against\n":                          "überspringe Submodul %s: es hat keinen Standard-Branch zum Vergleichen\n",
+		"failed to enter submodule %s: %v\n":                                                            "Wechsel in Submodul %s fehlgeschlagen: %v\n",
+		"== submodule %s (against %s) ==\n\n":                                                           "== Submodul %s (gegen %s) ==\n\n",
+		"--format and --output=%s can not be combined\n":                                                "--format und --output=%s können nicht kombiniert werden\n",
+		"invalid --format: %v\n":                                                                        "ungültiges --format: %v\n",
+		"MATCHED":                                                                                       "TREFFER",
+		"SUBJECT":                                                                                       "BETREFF",
+		"DELETED":                                                                                       "GELÖSCHT",
+		"yes":                                                                                           "ja",
+		"failed to assess the risk of deleting branches: %v\n":                                          "Bewertung des Löschrisikos fehlgeschlagen: %v\n",
+		"RISK: %s\n": "RISIKO: %s\n",
+		"keeping %s due to the risks above (see --accept-risk)\n":                                                             "%s wird wegen der obigen Risiken behalten (siehe --accept-risk)\n",
+		"failed to switch away from %s: %v\n":                                                                                 "Wechsel weg von %s fehlgeschlagen: %v\n",
+		"keeping %s since it is checked out (see --switch-away)\n":                                                            "%s wird behalten, da es ausgecheckt ist (siehe --switch-away)\n",
+		"%s is checked out; deleting it switches to %s first\n":                                                               "%s ist ausgecheckt; vor dem Löschen wird zu %s gewechselt\n",
+		"switching from %s to %s\n":                                                                                           "wechsle von %s zu %s\n",
+		"failed to read the alternate object directories: %v\n":                                                               "Alternative Objektverzeichnisse konnten nicht gelesen werden: %v\n",
+		"objects shared with %s were not counted, since they are stored there and deleting branches here doesn't free them\n": "Mit %s geteilte Objekte wurden nicht gezählt, da sie dort gespeichert sind und das Löschen von Branches hier sie nicht freigibt\n",
+		"usage: %s selftest\n":                                                                                                "Verwendung: %s selftest\n",
+		"selftest failed: %v\n":                                                                                               "Selbsttest fehlgeschlagen: %v\n",
+		"ok   %s (%s): %s\n":                                                                                                  "ok     %s (%s): %s\n",
+		"FAIL %s (%s): %s, expected %s\n":                                                                                     "FEHLER %s (%s): %s, erwartet: %s\n",
+		" or ":                                                                                                                " oder ",
+		"usage: %s analyze --branch <name>\n":                                                                                 "Verwendung: %s analyze --branch <Name>\n",
+		"--stdio can't be combined with a command\n":                                                                          "--stdio kann nicht mit einem Befehl kombiniert werden\n",
+		"failed to serve requests: %v\n":                                                                                      "Anfragen konnten nicht beantwortet werden: %v\n",
+		"failed to check whether refs changed: %v\n":                                                                          "Es konnte nicht geprüft werden, ob sich Refs geändert haben: %v\n",
+		"nothing will be deleted: %v\n":                                                                                       "es wird nichts gelöscht: %v\n",
+		"keeping %s since %d branches were already deleted (see --max-delete)\n":                                              "behalte %s, da bereits %d Branches gelöscht wurden (siehe --max-delete)\n",
+		"kept %d more branches which could be deleted, since --max-delete=%d was reached; review the deletions, then run again or raise --max-delete\n": "%d weitere löschbare Branches wurden behalten, da --max-delete=%d erreicht wurde; prüfen Sie die Löschungen und führen Sie das Programm erneut aus oder erhöhen Sie --max-delete\n",
+		"WARNING: %s is %d commits behind %s, so branches merged since will be missed; update it first (e.g. git pull)\n":                               "WARNUNG: %s liegt %d Commits hinter %s, daher werden seitdem gemergte Branches übersehen; aktualisieren Sie ihn zuerst (z. B. git pull)\n",
+		"refusing to analyze against a stale base: %v\n": "Analyse gegen eine veraltete Basis verweigert: %v\n",
+		"not deleting %s: %v\n":                          "%s wird nicht gelöscht: %v\n",
+		"WARNING: %s was rewritten since the last run (%s is no longer part of it); discarding the progress, queue, and cache computed from its old history\n": "WARNUNG: %s wurde seit dem letzten Lauf umgeschrieben (%s ist nicht mehr enthalten); Fortschritt, Warteschlange und Cache aus der alten Historie werden verworfen\n",
+		"failed to discard state from before %s was rewritten: %v\n":                       "Zustand von vor dem Umschreiben von %s konnte nicht verworfen werden: %v\n",
+		"failed to record the tip of %s: %v\n":                                             "Spitze von %s konnte nicht gespeichert werden: %v\n",
+		"usage: %s show <branch>\n":                                                        "Verwendung: %s show <Branch>\n",
+		"failed to show %s: %v\n":                                                          "%s konnte nicht angezeigt werden: %v\n",
+		"%s (subject score %.2f, diff score %.2f) compared with %s:\n":                     "%s (Betreff-Wert %.2f, Diff-Wert %.2f) verglichen mit %s:\n",
+		"... (%d more lines; see the show command)":                                        "... (%d weitere Zeilen; siehe den Befehl show)",
+		"failed to compare %s with %s: %v\n":                                               "%s konnte nicht mit %s verglichen werden: %v\n",
+		"how %s differs from %s:\n":                                                        "wie sich %s von %s unterscheidet:\n",
+		"failed to read the branches' cleanup trailers: %v\n":                              "die Cleanup-Trailer der Branches konnten nicht gelesen werden: %v\n",
+		"skipping %s: its tip asks to keep it\n":                                           "überspringe %s: seine Spitze verlangt, ihn zu behalten\n",
+		"%s is a revert, which was merged under %s (subject score: %f; diff score %f)\n":   "%s ist ein Revert, der unter %s gemergt wurde (Betreff-Score: %f; Diff-Score %f)\n",
+		"invalid config file %v\n":                                                         "ungültige Konfigurationsdatei %v\n",
+		"failed to detect branches merged into deleted branches: %v\n":                     "in gelöschte Branches gemergte Branches konnten nicht erkannt werden: %v\n",
+		"%s: %s, %d days old\n":                                                            "%s: %s, %d Tage alt\n",
+		"%s: %d merged branches, the oldest %d days old\n":                                 "%s: %d gemergte Branches, der älteste %d Tage alt\n",
+		"  %s (%d days)\n":                                                                 "  %s (%d Tage)\n",
+		"usage: %s report [--remote[=<remote>]] [--by-author]\n":                           "Verwendung: %s report [--remote[=<remote>]] [--by-author]\n",
+		"failed to list the branches of %s: %v\n":                                          "die Branches von %s konnten nicht aufgelistet werden: %v\n",
+		"invalid action inputs: %v\n":                                                      "ungültige Action-Eingaben: %v\n",
+		"--action can't be combined with --interactive\n":                                  "--action kann nicht mit --interactive kombiniert werden\n",
+		"failed to write the action's outputs: %v\n":                                       "die Ausgaben der Action konnten nicht geschrieben werden: %v\n",
+		"no branches need review\n":                                                        "keine Branches müssen geprüft werden\n",
+		"would open an issue listing %d branches\n":                                        "würde ein Issue mit %d Branches eröffnen\n",
+		"opened %s listing %d branches\n":                                                  "%s mit %d Branches eröffnet\n",
+		"usage: %s gitlab-review [--remote=<remote>] [--dry-run]\n":                        "Verwendung: %s gitlab-review [--remote=<remote>] [--dry-run]\n",
+		"failed to review the branches of %s: %v\n":                                        "Prüfung der Branches von %s fehlgeschlagen: %v\n",
+		"not deleting branches from %s: %v\n":                                              "Branches werden nicht von %s gelöscht: %v\n",
+		"failed to delete %s from %s: %v\n":                                                "%s konnte nicht von %s gelöscht werden: %v\n",
+		"would delete %s from %s too\n":                                                    "würde %s auch von %s löschen\n",
+		"%s also has: %s\n":                                                                "%s hat auch: %s\n",
+		"delete these %d branches from %s too? [y/N] ":                                     "diese %d Branches auch von %s löschen? [y/N] ",
+		"failed to load the scheduled deletions: %v\n":                                     "geplante Löschungen konnten nicht geladen werden: %v\n",
+		"failed to save the scheduled deletions: %v\n":                                     "geplante Löschungen konnten nicht gespeichert werden: %v\n",
+		"keeping %s: %v\n":                                                                 "behalte %s: %v\n",
+		"would schedule %s for deletion on %s\n":                                           "würde %s zur Löschung am %s vormerken\n",
+		"%s is scheduled for deletion on %s; pull request #%d is labelled %s until then\n": "%s ist zur Löschung am %s vorgemerkt; Pull-Request #%d trägt bis dahin das Label %s\n",
+		"%s is scheduled for deletion on %s\n":                                             "%s ist zur Löschung am %s vorgemerkt\n",
+		"keeping %s, since the %s label was removed from pull request #%d\n":               "behalte %s, da das Label %s von Pull-Request #%d entfernt wurde\n",
+		"%s may have been merged: its %s (see --delete-upstream-gone)\n":                   "%s wurde möglicherweise gemergt: sein %s (siehe --delete-upstream-gone)\n",
+		"fetching %s\n":                                                          "hole %s\n",
+		"not fetching %s: %v\n":                                                  "%s wird nicht geholt: %v\n",
+		"failed to fetch %s: %v\n":                                               "%s konnte nicht geholt werden: %v\n",
+		"failed to write the journal: %v\n":                                      "Journal konnte nicht geschrieben werden: %v\n",
+		"failed to restore: %v\n":                                                "Wiederherstellung fehlgeschlagen: %v\n",
+		"renaming branch %s to %s\n":                                             "benenne Branch %s in %s um\n",
+		"purged %d branches archived more than %d days ago\n":                    "%d Branches gelöscht, die vor mehr als %d Tagen archiviert wurden\n",
+		"usage: %s purge-archive [<days>]\n":                                     "Verwendung: %s purge-archive [<Tage>]\n",
+		"failed to purge the archive: %v\n":                                      "Archiv konnte nicht geleert werden: %v\n",
+		"archiving branch %s as %s\n":                                            "archiviere Branch %s als %s\n",
+		"--archive can't be combined with --archive-rename\n":                    "--archive kann nicht mit --archive-rename kombiniert werden\n",
+		"%s was %s\n":                                                            "%s wurde %s\n",
+		"would fast-forward %s to %s\n":                                          "würde %s auf %s vorspulen\n",
+		"not fast-forwarding %s on %s: %v\n":                                     "spule %s auf %s nicht vor: %v\n",
+		"%s was upstreamed: %s\n":                                                "%s wurde upstream übernommen: %s\n",
+		"fork-sync always deletes from the fork; --remote can't be used\n":       "fork-sync löscht immer aus dem Fork; --remote kann nicht verwendet werden\n",
+		"leaving GitLab unchanged: %v\n":                                         "GitLab bleibt unverändert: %v\n",
+		"restored %s from %s\n":                                                  "%s aus %s wiederhergestellt\n",
+		"leaving %s unchanged: %v\n":                                             "%s bleibt unverändert: %v\n",
+		"would reject %s\n":                                                      "würde %s ablehnen\n",
+		"failed to record the decision about %s: %v\n":                           "die Entscheidung über %s konnte nicht festgehalten werden: %v\n",
+		"would queue %d branches for review in %s\n":                             "würde %d Branches zur Prüfung in %s einreihen\n",
+		"expired %d HEAD reflog entries of the deleted branches older than %s\n": "%d HEAD-Reflog-Einträge der gelöschten Branches älter als %s sind abgelaufen\n",
+		"merged into %s (at %s), which was deleted as %s":                        "in %s (bei %s) gemergt, das gelöscht wurde als %s",
+		"contained in %s":                                                        "enthalten in %s",
+		"same commit as %s":                                                      "derselbe Commit wie %s",
+		"same diff as %s":                                                        "derselbe Diff wie %s",
+		"every commit has an equivalent in %s":                                   "jeder Commit hat eine Entsprechung in %s",
+		"protected: %s":                                                          "geschützt: %s",
+		"tracked by %s":                                                          "verwaltet von %s",
+		"active jj bookmark":                                                     "aktives jj-Bookmark",
+		"rejected in review":                                                     "bei der Prüfung abgelehnt",
+		"kept by a trailer of its tip":                                           "durch einen Trailer seiner Spitze behalten",
+		"upstream %s is gone":                                                    "Upstream %s ist verschwunden",
+		"%d unmerged commits":                                                    "%d nicht gemergte Commits",
+		"worktree %s":                                                            "Worktree %s",
+		"symbolic ref to %s":                                                     "symbolische Referenz auf %s",
+		"symbolic ref %s":                                                        "symbolische Referenz %s",
+		"conventional default branch":                                            "üblicher Standardbranch",
+		"current branch":                                                         "aktueller Branch",
+		"default branch of %s":                                                   "Standardbranch von %s",
+		"%s pattern %s":                                                          "%s-Muster %s",
+		"tracks %s":                                                              "folgt %s",
+		"BRANCH":                                                                 "BRANCH",
+		"STATUS":                                                                 "STATUS",
+		"DIFF":                                                                   "DIFF",
+		"DETAIL":                                                                 "DETAILS",
+	},
+}
+
+var catalog map[string]string
+
+// setLanguage selects the message catalog; lang may be a plain language ("de")
+// or a locale as found in $LANG ("de_DE.UTF-8"). When empty, $LANG is used.
+func setLanguage(lang string) {
+	if lang == "" {
+		lang = os.Getenv("LANG")
+	}
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "_.-@"); i >= 0 {
+		lang = lang[:i]
+	}
+	catalog = catalogs[lang]
+}
+
+// T returns the translation of a user-facing format string in the selected language
+func T(format string) string {
+	if translated, ok := catalog[format]; ok {
+		return translated
+	}
+	return format
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestReasonsTranslated checks that the reasons in the report are in the
+// report's language, like the rest of it
+func TestReasonsTranslated(t *testing.T) {
+	r := newTestRepo(t)
+	r.git("checkout", "--quiet", "-b", "work")
+	r.commit("Unmerged work", "f1.txt", 3, "work")
+	r.git("branch", "work-backup")
+	r.git("branch", "work-copy")
+	r.git("checkout", "--quiet", "main")
+
+	stdout, stderr, err := r.runCLI("--lang=de", "--dry-run", "--protect=work-copy")
+	if err != nil {
+		t.Fatalf("run failed: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "work-backup ist eine Sicherungskopie, enthalten in work (siehe --clean-backups)\n") {
+		t.Errorf("the backup's reason wasn't translated:\n%s", stdout)
+	}
+	stdout, stderr, err = r.runCLI("--lang=de", "--dry-run", "--protect=work-copy", "--output=json")
+	if err != nil {
+		t.Fatalf("run failed: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, `"reason": "geschützt: --protect"`) {
+		t.Errorf("the protected branch's reason wasn't translated:\n%s", stdout)
+	}
+}
+
+func TestTableHeaderTranslated(t *testing.T) {
+	defer setLanguage("en")
+	setLanguage("de")
+	var out strings.Builder
+	branches := []*BranchReport{
+		{Branch: "done", Classification: classMatched, Deleted: true, PotentialMerge: &PotentialMerge{MatchedSha: "0123456789abcdef", SubjectScore: 1, DiffScore: 0.95}},
+		{Branch: "done-backup", Classification: classBackup, Reason: "contained in done"},
+	}
+	if err := writeTable(&out, branches, 0); err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(out.String(), "\n")
+	if fields := strings.Fields(header); len(fields) == 0 || fields[0] != "BRANCH" || fields[len(fields)-1] != "DETAILS" {
+		t.Errorf("the header wasn't translated: %s", header)
+	}
+	for _, english := range []string{"MATCHED", "SUBJECT", "DELETED", "DETAIL "} {
+		if strings.Contains(header+" ", english) {
+			t.Errorf("%s wasn't translated: %s", english, header)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// refNamespace is the prefix of the refs in the namespace GIT_NAMESPACE selects,
+// e.g. refs/namespaces/foo/ for foo, or "" outside of a namespace. Git only
+// applies namespaces when serving a repository, so every other command sees
+// the namespace's refs under this prefix.
+var refNamespace = getRefNamespace(os.Getenv("GIT_NAMESPACE"))
+
+// getRefNamespace returns the ref prefix of a namespace, nesting it for each
+// component of a namespace such as foo/bar, as git does
+func getRefNamespace(namespace string) string {
+	prefix := ""
+	for _, component := range strings.Split(namespace, "/") {
+		if component != "" {
+			prefix += "refs/namespaces/" + component + "/"
+		}
+	}
+	return prefix
+}
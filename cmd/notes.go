@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// decisionsNotesRef holds review decisions as notes on the reviewed branch tips,
+// so they can be pushed and fetched like any other ref and shared by a team
+const decisionsNotesRef = "refs/notes/branch-cleanup-decisions"
+
+// DecisionNote is a line of a decisions note; a note holds one line per decision
+// made about the commit it is attached to
+type DecisionNote struct {
+	Branch   string    `json:"branch"`
+	Decision string    `json:"decision"`
+	Time     time.Time `json:"time"`
+}
+
+// recordDecision appends a review decision to the note on the branch's reviewed
+// tip, when deleting branches is permitted (see checkAllowed)
+func recordDecision(branch, tipSha, decision string) error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	line, err := json.Marshal(&DecisionNote{Branch: branch, Decision: decision, Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	return runCommand("git", "notes", "--ref", decisionsNotesRef, "append", "-m", string(line), tipSha)
+}
+
+// getBranchTips returns the sha of each local branch
+func getBranchTips() (map[string]string, error) {
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname) %(objectname)", branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	tips := map[string]string{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 2 {
+			tips[strings.TrimPrefix(parts[0], branchPrefix)] = parts[1]
+		}
+	}
+	return tips, nil
+}
+
+// addSharedRejections adds the branches whose current tip was rejected by
+// anyone sharing the decisions notes to rejected (branch -> rejected tip)
+func addSharedRejections(rejected map[string]string) error {
+	if _, err := getGitRevParse(decisionsNotesRef); err != nil {
+		return nil // no decisions have been recorded or fetched
+	}
+	lines, err := runCommandSplitLines("git", "notes", "--ref", decisionsNotesRef, "list")
+	if err != nil {
+		return err
+	}
+	notes := map[string]string{} // annotated commit -> note blob
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 2 {
+			notes[parts[1]] = parts[0]
+		}
+	}
+
+	tips, err := getBranchTips()
+	if err != nil {
+		return err
+	}
+	for branch, tip := range tips {
+		blob, ok := notes[tip]
+		if !ok {
+			continue
+		}
+		contents, err := runCommandSplitLines("git", "cat-file", "blob", blob)
+		if err != nil {
+			return err
+		}
+		// the latest decision about the branch wins
+		decision := ""
+		for _, line := range contents {
+			var note DecisionNote
+			if json.Unmarshal([]byte(line), &note) != nil {
+				continue // blank, or not written by us
+			}
+			if note.Branch == branch {
+				decision = note.Decision
+			}
+		}
+		if decision == decisionReject {
+			rejected[branch] = tip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// diff engines, which score how alike the branch's and a base commit's diffs are
+const (
+	engineJaroWinkler = "jaro-winkler" // character level comparison of the full diffs
+	engineNumstat     = "numstat"      // cosine similarity of the lines added and removed per file
+)
+
+var diffEngine = engineJaroWinkler
+
+// maxNumstatScore caps numstat scores: equal line counts don't prove equal
+// contents, so they must never reach the perfect score which deletes without review
+const maxNumstatScore = 0.99
+
+var commitNumstatCache = map[string]map[string]float64{}
+
+// parseNumstat turns git --numstat output into a vector with a dimension for
+// the lines added to, and removed from, each file
+func parseNumstat(lines []string) map[string]float64 {
+	vector := map[string]float64{}
+	for _, line := range lines {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		added, errAdded := strconv.Atoi(parts[0])
+		removed, errRemoved := strconv.Atoi(parts[1])
+		if errAdded != nil || errRemoved != nil {
+			added, removed = 1, 0 // binary files show as "-"
+		}
+		vector["+"+parts[2]] += float64(added)
+		vector["-"+parts[2]] += float64(removed)
+	}
+	return vector
+}
+
+func getNumstat(start, end string) (map[string]float64, error) {
+	lines, err := runCommandSplitLines(append([]string{"git", "--no-pager", "diff", "--numstat", "--no-renames", start + ".." + end, "--"}, ignoredPathspecs()...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseNumstat(lines), nil
+}
+
+func getCommitNumstat(commit string) (map[string]float64, error) {
+	if vector, ok := commitNumstatCache[commit]; ok {
+		return vector, nil
+	}
+	lines, err := runCommandSplitLines(append([]string{"git", "--no-pager", "show", "--numstat", "--no-renames", "--format=", commit, "--"}, ignoredPathspecs()...)...)
+	if err != nil {
+		return nil, err
+	}
+	vector := parseNumstat(lines)
+	commitNumstatCache[commit] = vector
+	return vector, nil
+}
+
+func cosineSimilarity(a, b map[string]float64) float32 {
+	var dot, normA, normB float64
+	for k, v := range a {
+		dot += v * b[k]
+		normA += v * v
+	}
+	for _, v := range b {
+		normB += v * v
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// numstatScore compares the branch's changes (start..end) with a base commit's
+func numstatScore(start, end, commit string) (float32, error) {
+	branchVector, err := getNumstat(start, end)
+	if err != nil {
+		return 0, err
+	}
+	commitVector, err := getCommitNumstat(commit)
+	if err != nil {
+		return 0, err
+	}
+	score := cosineSimilarity(branchVector, commitVector)
+	if score > maxNumstatScore {
+		score = maxNumstatScore
+	}
+	return score, nil
+}
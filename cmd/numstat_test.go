@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseNumstat(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected map[string]float64
+	}{
+		{"empty", nil, map[string]float64{}},
+		{"text", []string{"3\t1\tmain.go", "0\t5\tREADME.md"}, map[string]float64{"+main.go": 3, "-main.go": 1, "+README.md": 0, "-README.md": 5}},
+		{"binary", []string{"-\t-\tlogo.png"}, map[string]float64{"+logo.png": 1, "-logo.png": 0}},
+		{"tab in name", []string{"1\t0\tweird\tname"}, map[string]float64{"+weird\tname": 1, "-weird\tname": 0}},
+		{"malformed", []string{"", "3 1 main.go", "warning: something"}, map[string]float64{}},
+		{"repeated", []string{"1\t1\ta", "2\t0\ta"}, map[string]float64{"+a": 3, "-a": 1}},
+	}
+	for _, test := range tests {
+		if got := parseNumstat(test.lines); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: parseNumstat(%q) = %v, expected %v", test.name, test.lines, got, test.expected)
+		}
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     map[string]float64
+		expected float32
+	}{
+		{"identical", map[string]float64{"+a": 3, "-a": 1}, map[string]float64{"+a": 3, "-a": 1}, 1},
+		{"scaled", map[string]float64{"+a": 3, "-a": 1}, map[string]float64{"+a": 6, "-a": 2}, 1},
+		{"disjoint", map[string]float64{"+a": 3}, map[string]float64{"+b": 3}, 0},
+		{"empty", map[string]float64{}, map[string]float64{"+a": 1}, 0},
+		{"both empty", map[string]float64{}, map[string]float64{}, 0},
+		{"partial", map[string]float64{"+a": 1, "+b": 1}, map[string]float64{"+a": 1}, float32(1 / math.Sqrt2)},
+	}
+	for _, test := range tests {
+		got := cosineSimilarity(test.a, test.b)
+		if math.Abs(float64(got-test.expected)) > 1e-6 {
+			t.Errorf("%s: cosineSimilarity(%v, %v) = %v, expected %v", test.name, test.a, test.b, got, test.expected)
+		}
+		if reverse := cosineSimilarity(test.b, test.a); math.Abs(float64(reverse-got)) > 1e-6 {
+			t.Errorf("%s: cosineSimilarity isn't symmetric: %v and %v", test.name, got, reverse)
+		}
+	}
+}
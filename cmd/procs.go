@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procSemaphore caps the number of git (or jj) subprocesses running at once
+var procSemaphore = make(chan struct{}, 1)
+
+// maxLoad is the 1-minute load average above which spawning is delayed (0 disables)
+var maxLoad float64
+
+const (
+	loadBackoffStart = 100 * time.Millisecond
+	loadBackoffMax   = 2 * time.Second
+	loadBackoffLimit = 30 * time.Second
+)
+
+func setMaxProcs(n int) {
+	if n < 1 {
+		n = 1
+	}
+	procSemaphore = make(chan struct{}, n)
+}
+
+// readLoadAverage returns the 1-minute load average; it is only available on linux
+func readLoadAverage() (float64, bool) {
+	contents, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(contents))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return load, true
+}
+
+// waitForLoad backs off while the system load is above maxLoad, but gives up
+// waiting after loadBackoffLimit so a busy host slows a run rather than stalling it
+func waitForLoad() {
+	if maxLoad <= 0 {
+		return
+	}
+	backoff := loadBackoffStart
+	var waited time.Duration
+	for waited < loadBackoffLimit {
+		load, ok := readLoadAverage()
+		if !ok || load < maxLoad {
+			return
+		}
+		debugf("load average %.2f exceeds %.2f; waiting %s", load, maxLoad, backoff)
+		time.Sleep(backoff)
+		waited += backoff
+		backoff *= 2
+		if backoff > loadBackoffMax {
+			backoff = loadBackoffMax
+		}
+	}
+}
+
+func acquireProc() {
+	waitForLoad()
+	procSemaphore <- struct{}{}
+}
+
+func releaseProc() {
+	<-procSemaphore
+}
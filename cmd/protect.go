@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const remotePrefix = "refs/remotes/"
+
+// getGitConfigAll returns all values of a multi-valued config key (none when unset)
+func getGitConfigAll(key string) ([]string, error) {
+	lines, err := runCommandSplitLines("git", "config", "--get-all", key)
+	if err != nil {
+		// git config exits 1 when the key is unset
+		return nil, nil
+	}
+	values := []string{}
+	for _, line := range lines {
+		if v := strings.TrimSpace(line); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// getRemoteDefaultBranches returns the refs (e.g. refs/remotes/origin/main)
+// which each remote's HEAD points at
+func getRemoteDefaultBranches() ([]string, error) {
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname) %(symref)", remotePrefix)
+	if err != nil {
+		return nil, err
+	}
+	refs := []string{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 2 && strings.HasSuffix(parts[0], "/HEAD") {
+			refs = append(refs, parts[1])
+		}
+	}
+	return refs, nil
+}
+
+// getBranchUpstreams returns the upstream ref (e.g. refs/remotes/origin/main) of each local branch
+func getBranchUpstreams() (map[string]string, error) {
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname) %(upstream)", branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	upstreams := map[string]string{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 2 {
+			upstreams[strings.TrimPrefix(parts[0], branchPrefix)] = parts[1]
+		}
+	}
+	return upstreams, nil
+}
+
+// remoteBranchName strips the remote from a ref, e.g. refs/remotes/origin/main -> main
+func remoteBranchName(ref string) string {
+	parts := strings.SplitN(strings.TrimPrefix(ref, remotePrefix), "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// protectPatterns are the branch names and glob patterns (e.g. release/*)
+// given with --protect
+var protectPatterns []string
+
+// ProtectionRules are the names and patterns of branches which must never be deleted
+type ProtectionRules struct {
+	names       map[string]string // lowercase name -> reason
+	patterns    map[string]string // lowercase glob pattern (e.g. release/*) -> reason
+	defaultRefs map[string]bool   // remote HEADs, whose tracking branches are protected
+	upstreams   map[string]string // branch -> upstream ref
+}
+
+// getProtectionRules works out which branches must never be deleted. The
+// repo's default branch is protected under any name it is known by: main,
+// master, trunk, the name of any remote's HEAD, init.defaultBranch, or a local
+// branch tracking a remote's HEAD. So are the branches configured with
+// branch-cleanup.protected or --protect, or listed by a
+// branch-cleanup.protectCommand (one name or glob pattern per line each).
+// Names are compared case-insensitively, since refs on case-insensitive
+// filesystems alias each other.
+func getProtectionRules(currentBranch string) (*ProtectionRules, error) {
+	rules := &ProtectionRules{
+		names:       map[string]string{},
+		patterns:    map[string]string{},
+		defaultRefs: map[string]bool{},
+	}
+	for _, name := range []string{"main", "master", "trunk"} {
+		rules.names[name] = T("conventional default branch")
+	}
+	rules.names[strings.ToLower(currentBranch)] = T("current branch")
+
+	defaultRefs, err := getRemoteDefaultBranches()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range defaultRefs {
+		rules.defaultRefs[ref] = true
+		if name := remoteBranchName(ref); name != "" {
+			rules.names[strings.ToLower(name)] = fmt.Sprintf(T("default branch of %s"), strings.TrimPrefix(ref, remotePrefix))
+		}
+	}
+
+	defaultBranches, err := getGitConfigAll("init.defaultBranch")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range defaultBranches {
+		rules.names[strings.ToLower(name)] = "init.defaultBranch"
+	}
+
+	configured, err := getGitConfigAll("branch-cleanup.protected")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range configured {
+		rules.add(name, "branch-cleanup.protected")
+	}
+	for _, name := range protectPatterns {
+		rules.add(name, "--protect")
+	}
+
+	// e.g. to ask a forge which branches are deployed or protected server-side, since the repo itself can't tell
+	commands, err := getGitConfigAll("branch-cleanup.protectCommand")
+	if err != nil {
+		return nil, err
+	}
+	for _, command := range commands {
+		lines, err := runProtectCommand(command)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			rules.add(line, "branch-cleanup.protectCommand")
+		}
+	}
+
+	rules.upstreams, err = getBranchUpstreams()
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// add protects a branch name or glob pattern, given by source
+func (rules *ProtectionRules) add(name, source string) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "":
+	case strings.ContainsAny(name, "*?["):
+		rules.patterns[name] = fmt.Sprintf(T("%s pattern %s"), source, name)
+	default:
+		rules.names[name] = source
+	}
+}
+
+// reason returns why a branch is protected, if it is
+func (rules *ProtectionRules) reason(branch string) (string, bool) {
+	name := strings.ToLower(branch)
+	if reason, ok := rules.names[name]; ok {
+		return reason, true
+	}
+	for pattern, reason := range rules.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return reason, true
+		}
+	}
+	if upstream := rules.upstreams[branch]; rules.defaultRefs[upstream] {
+		return fmt.Sprintf(T("tracks %s"), strings.TrimPrefix(upstream, remotePrefix)), true
+	}
+	return "", false
+}
+
+// protectedBranches returns a map of branch name to the reason it must never be deleted
+func (rules *ProtectionRules) protectedBranches(branches []string) map[string]string {
+	protected := map[string]string{}
+	for _, branch := range branches {
+		if reason, ok := rules.reason(branch); ok {
+			protected[branch] = reason
+		}
+	}
+	return protected
+}
+
+const protectCommandCacheFileName = "protect-command-cache.json"
+
+// runProtectCommand runs a branch-cleanup.protectCommand, remembering its
+// output so that protection still holds when it can't be run, e.g. offline
+func runProtectCommand(command string) ([]string, error) {
+	path, err := getStateFilePath(protectCommandCacheFileName)
+	if err != nil {
+		return nil, err
+	}
+	cache := map[string][]string{}
+	if err := readJSONFile(path, &cache); err != nil {
+		return nil, err
+	}
+	lines, err := runCommandSplitLines("sh", "-c", command)
+	if err != nil {
+		cached, ok := cache[command]
+		if !ok {
+			return nil, fmt.Errorf("branch-cleanup.protectCommand %q failed: %w", command, err)
+		}
+		warnf(T("branch-cleanup.protectCommand %q failed (%v); using its output from the last successful run\n"), command, err)
+		return cached, nil
+	}
+	if checkWritable() != nil {
+		return lines, nil // the cache is only a fallback, and this run may not write
+	}
+	cache[command] = lines
+	if err := writeJSONFile(path, cache); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
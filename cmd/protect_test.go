@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestProtectionRulesReason(t *testing.T) {
+	rules := &ProtectionRules{
+		names:       map[string]string{"main": "conventional default branch"},
+		patterns:    map[string]string{},
+		defaultRefs: map[string]bool{remotePrefix + "origin/develop": true},
+		upstreams:   map[string]string{"dev": remotePrefix + "origin/develop", "topic": remotePrefix + "origin/topic"},
+	}
+	rules.add("  Release/*  ", "--protect")
+	rules.add("hotfix-??", "--protect")
+	rules.add("env/[ps]*", "branch-cleanup.protected")
+	rules.add("Staging", "branch-cleanup.protected")
+	rules.add("", "--protect")
+
+	tests := []struct {
+		branch string
+		reason string // "" when not protected
+	}{
+		{"main", "conventional default branch"},
+		{"MAIN", "conventional default branch"}, // refs alias each other on case-insensitive filesystems
+		{"staging", "branch-cleanup.protected"},
+		{"STAGING", "branch-cleanup.protected"},
+		{"release/1.0", "--protect pattern release/*"},
+		{"Release/2.0", "--protect pattern release/*"},
+		{"release/1.0/fix", ""}, // * doesn't cross a slash
+		{"release", ""},
+		{"hotfix-12", "--protect pattern hotfix-??"},
+		{"hotfix-123", ""},
+		{"env/prod", "branch-cleanup.protected pattern env/[ps]*"},
+		{"env/staging", "branch-cleanup.protected pattern env/[ps]*"},
+		{"env/dev", ""},
+		{"dev", "tracks origin/develop"},
+		{"topic", ""},
+		{"feature", ""},
+	}
+	for _, test := range tests {
+		reason, ok := rules.reason(test.branch)
+		if ok != (test.reason != "") || reason != test.reason {
+			t.Errorf("reason(%q) = %q, %v, expected %q", test.branch, reason, ok, test.reason)
+		}
+	}
+
+	branches := []string{}
+	for _, test := range tests {
+		branches = append(branches, test.branch)
+	}
+	protected := rules.protectedBranches(branches)
+	for _, test := range tests {
+		if protected[test.branch] != test.reason {
+			t.Errorf("protectedBranches gave %q for %q, expected %q", protected[test.branch], test.branch, test.reason)
+		}
+	}
+}
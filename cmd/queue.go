@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	queueFileName    = "queue.json"
+	rejectedFileName = "rejected.json"
+)
+
+// review decisions, filled in by a human editing the queue file
+const (
+	decisionApprove = "approve"
+	decisionReject  = "reject" // keep the branch, and stop suggesting it until its tip changes
+	decisionSkip    = "skip"   // keep the branch for now; it is queued again by the next queue run
+)
+
+// QueueEntry is a branch which needs a human to review it before it is deleted
+type QueueEntry struct {
+	Branch       string    `json:"branch"`
+	TipSha       string    `json:"tipSha"`
+	MatchedSha   string    `json:"matchedSha"`
+	SubjectScore float32   `json:"subjectScore"`
+	DiffScore    float32   `json:"diffScore"`
+	DiffCmd      string    `json:"diffCmd"`
+	Risks        []string  `json:"risks,omitempty"`     // as in the report; an approval is only carried out with --accept-risk
+	Directive    string    `json:"directive,omitempty"` // as in the report; with "delete", the branch's author accepted the risks
+	QueuedAt     time.Time `json:"queuedAt"`
+	Decision     string    `json:"decision"` // approve, reject, skip, or empty while undecided
+}
+
+// Rejection permanently keeps a branch out of the analysis, until its tip changes
+type Rejection struct {
+	Branch     string    `json:"branch"`
+	TipSha     string    `json:"tipSha"`
+	RejectedAt time.Time `json:"rejectedAt"`
+}
+
+func getStateFilePath(name string) (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, name), nil
+}
+
+// readJSONFile decodes path into v, leaving v untouched when path does not exist
+func readJSONFile(path string, v interface{}) error {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(contents, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := writeJSON(f, v); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func getQueuePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	return getStateFilePath(queueFileName)
+}
+
+// loadRejections returns the rejected tip sha of each branch
+func loadRejections() (map[string]string, error) {
+	path, err := getStateFilePath(rejectedFileName)
+	if err != nil {
+		return nil, err
+	}
+	var rejections []*Rejection
+	if err := readJSONFile(path, &rejections); err != nil {
+		return nil, err
+	}
+	rejected := map[string]string{}
+	for _, r := range rejections {
+		rejected[r.Branch] = r.TipSha
+	}
+	return rejected, nil
+}
+
+// addRejections records more rejected branches, when deleting branches is
+// permitted (see checkAllowed)
+func addRejections(newRejections []*Rejection) error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	path, err := getStateFilePath(rejectedFileName)
+	if err != nil {
+		return err
+	}
+	var rejections []*Rejection
+	if err := readJSONFile(path, &rejections); err != nil {
+		return err
+	}
+	return writeJSONFile(path, append(rejections, newRejections...))
+}
+
+// queueCandidates appends the branches which need review to the queue file,
+// skipping any already queued at the same tip, unless writeDisabled says why
+// nothing may be written (e.g. --dry-run)
+func queueCandidates(path string, report *Report, writeDisabled error) error {
+	path, err := getQueuePath(path)
+	if err != nil {
+		return err
+	}
+	var queue []*QueueEntry
+	if err := readJSONFile(path, &queue); err != nil {
+		return err
+	}
+	queued := map[string]bool{}
+	for _, e := range queue {
+		queued[e.Branch+" "+e.TipSha] = true
+	}
+
+	added := 0
+	for _, b := range report.Branches {
+		if b.Classification != classPotential || queued[b.Branch+" "+b.TipSha] {
+			continue
+		}
+		queue = append(queue, &QueueEntry{
+			Branch:       b.Branch,
+			TipSha:       b.TipSha,
+			MatchedSha:   b.MatchedSha,
+			SubjectScore: b.SubjectScore,
+			DiffScore:    b.DiffScore,
+			DiffCmd:      b.DiffCmd,
+			Risks:        b.Risks,
+			Directive:    b.Directive,
+			QueuedAt:     report.Time,
+		})
+		added++
+	}
+	if writeDisabled != nil {
+		reportf(T("would queue %d branches for review in %s\n"), added, path)
+		return nil
+	}
+	if err := writeJSONFile(path, queue); err != nil {
+		return err
+	}
+	infof(T("queued %d branches for review in %s\n"), added, path)
+	infof(T("set \"decision\" to \"%s\", \"%s\", or \"%s\", then run the resolve command\n"), decisionApprove, decisionReject, decisionSkip)
+	return nil
+}
+
+// resolveQueue deletes the approved branches, permanently records the rejected
+// ones, and drops the skipped ones from the queue; every decision is also
+// recorded in the shared decisions notes (see decisionsNotesRef). Undecided
+// entries are left in the queue. An approval only applies to the tip which was
+// reviewed, so branches which have moved since are left in the queue too, as
+// are risky ones unless the risk is accepted, and those which failed to be
+// deleted. When deleting is disabled (e.g. --dry-run), what would be done is
+// reported instead, and nothing is written.
+func resolveQueue(rs *runState, path string) error {
+	path, err := getQueuePath(path)
+	if err != nil {
+		return err
+	}
+	var queue []*QueueEntry
+	if err := readJSONFile(path, &queue); err != nil {
+		return err
+	}
+	// check every decision up front, so a typo doesn't leave the queue half resolved
+	for _, e := range queue {
+		switch e.Decision {
+		case "", decisionApprove, decisionReject, decisionSkip:
+		default:
+			return fmt.Errorf("%s: unknown decision %q for %s", path, e.Decision, e.Branch)
+		}
+	}
+	dryRun := rs.deleteDisabled != nil
+	if dryRun {
+		infof(T("leaving %s unchanged: %v\n"), path, rs.deleteDisabled)
+	}
+
+	remaining := []*QueueEntry{}
+	rejections := []*Rejection{}
+	deleted, failed := 0, 0
+	for _, e := range queue {
+		switch e.Decision {
+		case "":
+			remaining = append(remaining, e)
+		case decisionApprove:
+			tip, err := getGitRevParse(branchRef(e.Branch))
+			if err != nil {
+				reportf(T("%s no longer exists\n"), e.Branch)
+				continue
+			}
+			if tip != e.TipSha {
+				reportf(T("%s has changed since it was reviewed; keeping it\n"), e.Branch)
+				e.Decision = ""
+				remaining = append(remaining, e)
+				continue
+			}
+			// e.g. the matched commit was force-pushed out of the base since it was reviewed
+			b := &BranchReport{Branch: e.Branch, PotentialMerge: &PotentialMerge{TipSha: e.TipSha, MatchedSha: e.MatchedSha}}
+			if err := rs.verifyUnchanged(b); err != nil {
+				reportf(T("not deleting %s: %v\n"), e.Branch, err)
+				e.Decision = ""
+				remaining = append(remaining, e)
+				continue
+			}
+			if len(e.Risks) > 0 && !rs.progOpts.AcceptRisk && e.Directive != directiveDelete {
+				for _, risk := range e.Risks {
+					reportf(T("RISK: %s\n"), risk)
+				}
+				reportf(T("keeping %s due to the risks above (see --accept-risk)\n"), e.Branch)
+				remaining = append(remaining, e)
+				continue
+			}
+			if dryRun {
+				reportf(T("would delete branch %s\n"), e.Branch)
+				continue
+			}
+			reason := fmt.Sprintf("approved in review as %s (subject score %.2f, diff score %.2f)", e.MatchedSha, e.SubjectScore, e.DiffScore)
+			err = rs.removeBranch(&Receipt{Branch: e.Branch, Reason: reason, MatchedSha: e.MatchedSha, SubjectScore: e.SubjectScore, DiffScore: e.DiffScore})
+			if errors.Is(err, errMaxDelete) {
+				reportf(T("keeping %s since %d branches were already deleted (see --max-delete)\n"), e.Branch, deleted)
+				remaining = append(remaining, e)
+				continue
+			}
+			if err != nil {
+				// the approval still stands, so the next resolve retries it
+				warnf(T("failed to delete branch %s: %v\n"), e.Branch, err)
+				failed++
+				remaining = append(remaining, e)
+				continue
+			}
+			deleted++
+			if err := recordDecision(e.Branch, e.TipSha, e.Decision); err != nil {
+				warnf(T("failed to record the decision about %s: %v\n"), e.Branch, err)
+			}
+		case decisionReject:
+			if dryRun {
+				reportf(T("would reject %s\n"), e.Branch)
+				continue
+			}
+			if err := recordDecision(e.Branch, e.TipSha, e.Decision); err != nil {
+				warnf(T("failed to record the decision about %s: %v\n"), e.Branch, err)
+				failed++
+				remaining = append(remaining, e)
+				continue
+			}
+			reportf(T("%s will no longer be suggested for deletion\n"), e.Branch)
+			rejections = append(rejections, &Rejection{Branch: e.Branch, TipSha: e.TipSha, RejectedAt: time.Now()})
+		case decisionSkip:
+			reportf(T("skipping %s for now\n"), e.Branch)
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	if len(rejections) > 0 {
+		if err := addRejections(rejections); err != nil {
+			return err
+		}
+	}
+	if err := writeJSONFile(path, remaining); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d decisions failed to be carried out, and were left in %s", failed, path)
+	}
+	return nil
+}
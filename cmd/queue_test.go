@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newQueueRepo returns a repo with a queue approving the deletion of done and
+// wt, which is checked out in another worktree so it can't be deleted, and
+// rejecting kept
+func newQueueRepo(t *testing.T) (*testRepo, string) {
+	r := newTestRepo(t)
+	for i, branch := range []string{"done", "wt", "kept"} {
+		r.git("checkout", "--quiet", "-b", branch, "main")
+		r.commit("Change "+branch, "f1.txt", 10+i, branch)
+	}
+	r.git("checkout", "--quiet", "main")
+	r.git("worktree", "add", "--quiet", filepath.Join(filepath.Dir(r.dir), "wt"), "wt")
+	main := r.git("rev-parse", "main")
+
+	queue := []*QueueEntry{
+		{Branch: "wt", TipSha: r.git("rev-parse", "wt"), MatchedSha: main, Decision: decisionApprove},
+		{Branch: "done", TipSha: r.git("rev-parse", "done"), MatchedSha: main, Decision: decisionApprove},
+		{Branch: "kept", TipSha: r.git("rev-parse", "kept"), Decision: decisionReject},
+	}
+	contents, err := json.Marshal(queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(filepath.Dir(r.dir), "queue.json")
+	if err := os.WriteFile(path, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return r, path
+}
+
+func readQueue(t *testing.T, path string) []*QueueEntry {
+	t.Helper()
+	var queue []*QueueEntry
+	if err := readJSONFile(path, &queue); err != nil {
+		t.Fatal(err)
+	}
+	return queue
+}
+
+func TestResolveQueueDryRun(t *testing.T) {
+	r, path := newQueueRepo(t)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err := r.runCLI("--dry-run", "resolve", path)
+	if err != nil {
+		t.Fatalf("resolve --dry-run failed: %v\n%s", err, stderr)
+	}
+	for _, expected := range []string{"would delete branch done\n", "would delete branch wt\n", "would reject kept\n"} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("expected %q in:\n%s", expected, stdout)
+		}
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("the queue was rewritten:\n%s", after)
+	}
+	for _, branch := range []string{"done", "wt", "kept"} {
+		if !r.hasBranch(branch) {
+			t.Errorf("%s was deleted", branch)
+		}
+	}
+	if notes := r.git("for-each-ref", decisionsNotesRef); notes != "" {
+		t.Errorf("decisions were recorded: %s", notes)
+	}
+	stateDir := r.git("rev-parse", "--git-common-dir")
+	if _, err := os.Stat(filepath.Join(r.dir, stateDir, "branch-cleanup", rejectedFileName)); err == nil {
+		t.Errorf("%s was written", rejectedFileName)
+	}
+}
+
+// TestResolveQueueFailure checks that a branch which fails to be deleted is
+// reported, and left in the queue, without keeping the rest from being resolved
+func TestResolveQueueFailure(t *testing.T) {
+	r, path := newQueueRepo(t)
+	_, stderr, err := r.runCLI("resolve", path)
+	if err == nil {
+		t.Fatalf("resolve succeeded, though wt couldn't be deleted:\n%s", stderr)
+	}
+	if !strings.Contains(stderr, "failed to delete branch wt") {
+		t.Errorf("the failure to delete wt wasn't reported:\n%s", stderr)
+	}
+	if r.hasBranch("done") {
+		t.Errorf("done wasn't deleted:\n%s", stderr)
+	}
+	if !r.hasBranch("wt") {
+		t.Errorf("wt was deleted")
+	}
+	queue := readQueue(t, path)
+	if len(queue) != 1 || queue[0].Branch != "wt" || queue[0].Decision != decisionApprove {
+		t.Errorf("expected only the approval of wt to be left in the queue: %+v", queue)
+	}
+	rejected := r.git("notes", "--ref", decisionsNotesRef, "show", "kept")
+	if !strings.Contains(rejected, `"decision":"reject"`) {
+		t.Errorf("the rejection of kept wasn't recorded: %s", rejected)
+	}
+}
+
+// TestQueueReportOnly checks that review decisions aren't written where
+// branch-cleanup.allow only permits reports
+func TestQueueReportOnly(t *testing.T) {
+	r, path := newQueueRepo(t)
+	r.git("config", "branch-cleanup.allow", opReportOnly)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, err := r.runCLI("resolve", path); err != nil {
+		t.Fatalf("resolve failed: %v\n%s", err, stderr)
+	}
+	if after, err := os.ReadFile(path); err != nil || string(after) != string(before) {
+		t.Errorf("the queue was rewritten: %v\n%s", err, after)
+	}
+	if notes := r.git("for-each-ref", decisionsNotesRef); notes != "" {
+		t.Errorf("decisions were recorded: %s", notes)
+	}
+
+	fresh := filepath.Join(filepath.Dir(r.dir), "fresh.json")
+	if _, stderr, err := r.runCLI("queue", fresh); err != nil {
+		t.Fatalf("queue failed: %v\n%s", err, stderr)
+	}
+	if _, err := os.Stat(fresh); err == nil {
+		t.Errorf("%s was written", fresh)
+	}
+
+	r.enter()
+	if err := recordDecision("kept", r.git("rev-parse", "kept"), decisionReject); err == nil {
+		t.Errorf("recordDecision succeeded")
+	}
+	if err := addRejections([]*Rejection{{Branch: "kept", TipSha: r.git("rev-parse", "kept")}}); err == nil {
+		t.Errorf("addRejections succeeded")
+	}
+}
+
+// TestResolveQueueOptionsAfterCommand checks that options given after the
+// command apply, rather than being taken for the queue's path
+func TestResolveQueueOptionsAfterCommand(t *testing.T) {
+	r, path := newQueueRepo(t)
+	stdout, stderr, err := r.runCLI("resolve", "--dry-run", path)
+	if err != nil {
+		t.Fatalf("resolve --dry-run failed: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "would delete branch done\n") || !r.hasBranch("done") {
+		t.Errorf("--dry-run didn't apply:\n%s", stdout)
+	}
+	if _, err := os.Stat(filepath.Join(r.dir, "--dry-run")); err == nil {
+		t.Errorf("--dry-run was taken for the queue's path")
+	}
+	if _, stderr, err := r.runCLI("queue", "--no-such-option"); err == nil {
+		t.Errorf("queue accepted an unknown option:\n%s", stderr)
+	}
+}
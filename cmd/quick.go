@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+)
+
+// getGoneUpstreams returns the upstream of each branch whose upstream ref no longer exists
+func getGoneUpstreams() (map[string]string, error) {
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname) %(upstream) %(upstream:track)", branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	gone := map[string]string{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) == 3 && parts[2] == "[gone]" {
+			gone[strings.TrimPrefix(parts[0], branchPrefix)] = parts[1]
+		}
+	}
+	return gone, nil
+}
+
+// quickClassify classifies a branch from its ancestry alone, for --quick. It
+// returns nil for the potential merge unless the branch is merged.
+func quickClassify(baseRev, branch string) (string, *PotentialMerge, error) {
+	ref := branchRef(branch)
+	base, err := getGitMergeBase(baseRev, ref)
+	if err != nil {
+		return "", nil, err
+	}
+	branchSha, err := getGitRevParse(ref)
+	if err != nil {
+		return "", nil, err
+	}
+	if base != branchSha {
+		return classUnknown, nil, nil
+	}
+	potentialMerged := &PotentialMerge{
+		Branch:       branch,
+		TipSha:       branchSha,
+		MergeBase:    base,
+		MergedSha:    base,
+		Merged:       true,
+		AtBaseTip:    base == baseRev,
+		SubjectScore: 1.00,
+		DiffScore:    1.00,
+	}
+	if potentialMerged.AtBaseTip {
+		return classTrackingDuplicate, potentialMerged, nil
+	}
+	return classMerged, potentialMerged, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// readOnly is set by GIT_BRANCH_CLEANUP_READONLY, e.g. machine-wide on shared
+// build infrastructure where only reporting should ever happen. It disables
+// everything which changes branches, remotes, config, or objects, whatever
+// the flags; such runs report what they would have deleted instead.
+var readOnly = isTruthy(os.Getenv("GIT_BRANCH_CLEANUP_READONLY"))
+
+var errReadOnly = errors.New("GIT_BRANCH_CLEANUP_READONLY is set, so nothing may be changed")
+
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// checkWritable fails when the repository may not be changed; it guards every
+// destructive operation, so the flags asking for one can never bypass it
+func checkWritable() error {
+	if readOnly {
+		return errReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const journalFileName = "journal.json"
+
+// receiptPrefix starts each receipt line of a text report; restore accepts the
+// line with or without it
+const receiptPrefix = "receipt: "
+
+// Receipt records a deletion, with what is needed to undo it. A text report
+// prints it as a line of JSON, which can be pasted into the restore command.
+type Receipt struct {
+	Branch       string    `json:"branch"`
+	TipSha       string    `json:"tipSha"`
+	Reason       string    `json:"reason"` // why it was deleted, as in the deletion log
+	MatchedSha   string    `json:"matchedSha,omitempty"`
+	SubjectScore float32   `json:"subjectScore,omitempty"`
+	DiffScore    float32   `json:"diffScore,omitempty"`
+	DeletedAt    time.Time `json:"deletedAt"`
+	ArchivedAs   string    `json:"archivedAs,omitempty"` // with --archive-rename, the branch's name in the archive
+	Restore      string    `json:"restore"`              // the command which recreates the branch
+}
+
+// newReportReceipt returns the receipt of a reported branch about to be
+// deleted; the rest is filled in once it is (see runState.removeBranch)
+func newReportReceipt(b *BranchReport) *Receipt {
+	r := &Receipt{Branch: b.Branch, Reason: describeDeletion(b)}
+	if b.PotentialMerge != nil && b.MatchedSha != "" {
+		r.MatchedSha = b.MatchedSha
+		r.SubjectScore = b.SubjectScore
+		r.DiffScore = b.DiffScore
+	}
+	return r
+}
+
+// JournalRun is the receipts of a run's deletions
+type JournalRun struct {
+	Time     time.Time  `json:"time"`
+	Base     string     `json:"base"`
+	Receipts []*Receipt `json:"receipts"`
+}
+
+func readJournal() ([]*JournalRun, error) {
+	path, err := getStateFilePath(journalFileName)
+	if err != nil {
+		return nil, err
+	}
+	var journal []*JournalRun
+	return journal, readJSONFile(path, &journal)
+}
+
+// saveJournalRun writes a run's receipts to the journal, which keeps every
+// run's; it is saved after each deletion, so a run which is interrupted is
+// journaled too
+func saveJournalRun(run *JournalRun) error {
+	journal, err := readJournal()
+	if err != nil {
+		return err
+	}
+	if n := len(journal); n > 0 && journal[n-1].Time.Equal(run.Time) {
+		journal[n-1] = run
+	} else {
+		journal = append(journal, run)
+	}
+	path, err := getStateFilePath(journalFileName)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(path, journal)
+}
+
+// findDeletion returns the receipt of the last deletion of branch, from the
+// journal, or else the deletion log (which predates the journal, though its
+// entries expire with the reflog), or else the tips --archive kept
+func findDeletion(branch string) (*Receipt, error) {
+	journal, err := readJournal()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(journal) - 1; i >= 0; i-- {
+		receipts := journal[i].Receipts
+		for j := len(receipts) - 1; j >= 0; j-- {
+			if receipts[j].Branch == branch {
+				return receipts[j], nil
+			}
+		}
+	}
+	tips, err := getDeletedTips()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tips {
+		if t.Branch == branch {
+			return &Receipt{Branch: t.Branch, TipSha: t.Tip, Reason: t.Reason}, nil
+		}
+	}
+	tip, err := findArchivedTip(branch)
+	if err != nil {
+		return nil, err
+	}
+	if tip != "" {
+		return &Receipt{Branch: branch, TipSha: tip, Reason: "archived"}, nil
+	}
+	return nil, fmt.Errorf("no deletion of %s was recorded", branch)
+}
+
+// parseReceipts reads the receipts pasted into restore, one per line; other
+// lines, such as the rest of a report, are ignored
+func parseReceipts(r io.Reader) ([]*Receipt, error) {
+	receipts := []*Receipt{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), receiptPrefix)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var receipt Receipt
+		if err := json.Unmarshal([]byte(line), &receipt); err != nil {
+			return nil, fmt.Errorf("invalid receipt %s: %w", line, err)
+		}
+		if receipt.Branch == "" || receipt.TipSha == "" {
+			return nil, fmt.Errorf("invalid receipt %s: it has no branch or tip", line)
+		}
+		receipts = append(receipts, &receipt)
+	}
+	return receipts, scanner.Err()
+}
+
+// restoreBranch recreates a deleted branch at tip, unless a branch of that
+// name exists again
+func restoreBranch(branch, tip string) error {
+	if current, err := getGitRevParse(branchRef(branch)); err == nil {
+		if current != tip {
+			warnf(T("not restoring %s: it has been recreated at %s\n"), branch, current)
+		}
+		return nil
+	}
+	if err := runCommand("git", "update-ref", branchRef(branch), tip, ""); err != nil {
+		return fmt.Errorf("failed to restore %s at %s: %w", branch, tip, err)
+	}
+	reportf(T("restored %s at %s\n"), branch, tip)
+	return nil
+}
+
+// unarchiveBranch renames an archived branch back, unless it moved since, or
+// is gone, in which case the branch is recreated at tip
+func unarchiveBranch(branch, archived, tip string) error {
+	if current, err := getGitRevParse(branchRef(archived)); err != nil || current != tip {
+		return restoreBranch(branch, tip)
+	}
+	if current, err := getGitRevParse(branchRef(branch)); err == nil {
+		warnf(T("not restoring %s: it has been recreated at %s\n"), branch, current)
+		return nil
+	}
+	if err := renameBranch(archived, branch); err != nil {
+		return fmt.Errorf("failed to rename %s back to %s: %w", archived, branch, err)
+	}
+	reportf(T("restored %s from %s\n"), branch, archived)
+	return nil
+}
+
+// restore recreates deleted branches, given by name (the last deletion of
+// each is undone), or by receipt; with no arguments, receipts are read from r
+func restore(args []string, r io.Reader) error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	receipts := []*Receipt{}
+	var pasted []string
+	for _, arg := range args {
+		if strings.HasPrefix(strings.TrimPrefix(strings.TrimSpace(arg), receiptPrefix), "{") {
+			pasted = append(pasted, arg)
+			continue
+		}
+		receipt, err := findDeletion(arg)
+		if err != nil {
+			return err
+		}
+		receipts = append(receipts, receipt)
+	}
+	if len(pasted) > 0 || len(args) == 0 {
+		if len(pasted) > 0 {
+			r = strings.NewReader(strings.Join(pasted, "\n"))
+		}
+		parsed, err := parseReceipts(r)
+		if err != nil {
+			return err
+		}
+		receipts = append(receipts, parsed...)
+	}
+	if len(receipts) == 0 {
+		return fmt.Errorf("no receipts were given")
+	}
+	for _, receipt := range receipts {
+		if receipt.ArchivedAs != "" {
+			if err := unarchiveBranch(receipt.Branch, receipt.ArchivedAs, receipt.TipSha); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := restoreBranch(receipt.Branch, receipt.TipSha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// getRefsStamp summarizes the modification times of where git stores refs, so
+// long-running modes can tell whether any branch (or HEAD) may have changed
+// without listing them. Git updates loose refs by renaming a lock file into
+// place, which changes the mtime of the directory holding the ref, so it is
+// enough to look at the directories, packed-refs, and HEAD.
+func getRefsStamp() (string, error) {
+	commonDir, err := getGitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	head, err := getGitPath("HEAD")
+	if err != nil {
+		return "", err
+	}
+	stamp := []string{}
+	add := func(path string, info fs.FileInfo) {
+		stamp = append(stamp, fmt.Sprintf("%s %d %d", path, info.ModTime().UnixNano(), info.Size()))
+	}
+	for _, path := range []string{head, filepath.Join(commonDir, "packed-refs")} {
+		if info, err := os.Stat(path); err == nil {
+			add(path, info)
+		}
+	}
+	for _, dir := range []string{"refs", "reftable"} {
+		err := filepath.WalkDir(filepath.Join(commonDir, dir), func(path string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil || !d.IsDir() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			add(path, info)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(stamp, "\n"), nil
+}
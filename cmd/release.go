@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// showReleasedIn lists the branches whose contents are entirely reachable from
+// (or matched within) the history leading up to a release tag. Nothing is deleted.
+func showReleasedIn(tag string, progOpts *opts) error {
+	baseRev, err := resolveBase(tag)
+	if err != nil {
+		return err
+	}
+	branches, err := getBranches()
+	if err != nil {
+		return err
+	}
+
+	report := Report{SchemaVersion: reportSchemaVersion, Time: time.Now(), Base: tag}
+	for _, branch := range branches {
+		potentialMerged, err := analyzeBranch(baseRev, branch)
+		if err != nil {
+			if progOpts.Strict {
+				return fmt.Errorf("failed to analyze %s: %w", branch, err)
+			}
+			warnf(T("ignoring %s due to: %s\n"), branch, err)
+			continue
+		}
+		if potentialMerged == nil {
+			continue
+		}
+		classification := classify(potentialMerged, progOpts)
+		switch classification {
+		case classMerged, classTrackingDuplicate, classMatched, classRevert:
+			report.add(branch, classification, potentialMerged)
+		}
+	}
+	if err := sortBranchReports(report.Branches, progOpts.Sort); err != nil {
+		return err
+	}
+
+	if reportJSON {
+		return writeJSON(os.Stdout, &report)
+	}
+	if reportTable {
+		return writeReportTable(report.Branches)
+	}
+	if reportTemplate != nil {
+		return writeFormatted(os.Stdout, report.Branches)
+	}
+	for _, b := range report.Branches {
+		switch b.Classification {
+		case classMatched, classRevert:
+			reportf(T("%s was released in %s as %s\n"), b.Branch, tag, b.MatchedSha)
+		default:
+			reportf(T("%s was released in %s\n"), b.Branch, tag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RemoteCopy is a local branch's branch on the remote --remote names, which is
+// deleted along with it
+type RemoteCopy struct {
+	Name   string // the branch's name on the remote
+	TipSha string // where its remote-tracking branch points
+}
+
+// getRemoteCopies returns the branches of remote which the local branches were
+// pushed to: their upstream, when it is on remote, or else the branch of the
+// same name. Like upstreams, they must be looked up before deleting. The
+// remote's default branch is never a copy.
+func (rs *runState) getRemoteCopies(remote string) (map[string]*RemoteCopy, error) {
+	prefix := remotePrefix + remote + "/"
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname) %(objectname) %(symref)", prefix)
+	if err != nil {
+		return nil, err
+	}
+	tips := map[string]string{}
+	defaultBranch := ""
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		name := strings.TrimPrefix(parts[0], prefix)
+		if len(parts) == 3 {
+			defaultBranch = strings.TrimPrefix(parts[2], prefix) // from HEAD -> main
+			continue
+		}
+		tips[name] = parts[1]
+	}
+	upstreams, err := getBranchUpstreams()
+	if err != nil {
+		return nil, err
+	}
+
+	copies := map[string]*RemoteCopy{}
+	for _, branch := range rs.branches {
+		name := branch
+		if upstream := upstreams[branch]; strings.HasPrefix(upstream, prefix) {
+			name = strings.TrimPrefix(upstream, prefix)
+		}
+		if tip, ok := tips[name]; ok && name != defaultBranch {
+			copies[branch] = &RemoteCopy{Name: name, TipSha: tip}
+		}
+	}
+	return copies, nil
+}
+
+// deleteRemoteCopy deletes the remote copy of a branch which was deleted
+// locally at localTip. The remote may only lose what was confirmed merged, so
+// the copy must not have commits the local branch lacked, nor have been pushed
+// to since it was fetched.
+func (rs *runState) deleteRemoteCopy(remote, localTip string, c *RemoteCopy) error {
+	if _, protected := rs.protection.reason(c.Name); protected {
+		return fmt.Errorf("%s is protected on %s", c.Name, remote)
+	}
+	if !isAncestor(c.TipSha, localTip) {
+		return fmt.Errorf("%s/%s has commits the local branch lacks", remote, c.Name)
+	}
+	infof(T("deleting %s from %s\n"), c.Name, remote)
+	ref := "refs/heads/" + c.Name
+	_, stderr, err := runCommandWithStderr("git", "push", "--quiet", "--delete", "--force-with-lease="+ref+":"+c.TipSha, remote, ref)
+	if err != nil && stderr != "" {
+		return fmt.Errorf("%s", strings.TrimPrefix(strings.SplitN(stderr, "\n", 2)[0], "error: "))
+	}
+	return err
+}
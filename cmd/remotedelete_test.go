@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRemoteDeleteClasses runs --remote against each class a branch can be
+// deleted as without its history having been analyzed, which leaves its report
+// without a potential merge
+func TestRemoteDeleteClasses(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(r *testRepo) string // builds the branch, returning its name
+		options []string
+	}{
+		{
+			name: "backup",
+			setup: func(r *testRepo) string {
+				r.git("checkout", "--quiet", "-b", "work")
+				r.commit("Unmerged work", "f1.txt", 3, "work")
+				r.git("branch", "work-backup")
+				r.git("checkout", "--quiet", "main")
+				return "work-backup"
+			},
+			options: []string{"--quick", "--clean-backups"},
+		},
+		{
+			name: "duplicate",
+			setup: func(r *testRepo) string {
+				r.git("checkout", "--quiet", "-b", "aaa")
+				r.commit("Unmerged work", "f1.txt", 3, "work")
+				r.git("branch", "zzz")
+				r.git("checkout", "--quiet", "main")
+				return "zzz"
+			},
+			options: []string{"--delete-duplicates"},
+		},
+		{
+			name: "upstream-gone",
+			setup: func(r *testRepo) string {
+				// tracking a branch of another remote which was deleted, while origin has a copy of the same name
+				r.git("remote", "add", "other", r.origin)
+				r.git("checkout", "--quiet", "-b", "gone")
+				r.commit("Landed elsewhere", "f2.txt", 5, "gone")
+				r.git("update-ref", "refs/remotes/other/gone", "HEAD")
+				r.git("branch", "--quiet", "--set-upstream-to=other/gone")
+				r.git("update-ref", "-d", "refs/remotes/other/gone")
+				r.git("checkout", "--quiet", "main")
+				return "gone"
+			},
+			options: []string{"--delete-upstream-gone"},
+		},
+		{
+			name: "merged-into-deleted",
+			setup: func(r *testRepo) string {
+				r.git("checkout", "--quiet", "-b", "outer")
+				r.commit("Outer one", "f2.txt", 2, "outer one")
+				r.git("branch", "inner")
+				r.commit("Outer two", "f2.txt", 22, "outer two")
+				r.git("checkout", "--quiet", "main")
+				r.git("merge", "--quiet", "--squash", "outer")
+				r.git("commit", "--quiet", "-m", "Outer")
+				r.git("push", "--quiet", "origin", "main", "inner")
+				r.git("fetch", "--quiet", "origin")
+				if stdout, stderr, err := r.runCLI("--accept-risk"); err != nil || r.hasBranch("outer") {
+					r.t.Fatalf("outer was not deleted: %v\n%s\n%s", err, stdout, stderr)
+				}
+				return "inner"
+			},
+			options: []string{"--quick"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			branch := test.setup(r)
+			r.git("push", "--quiet", "origin", "main", branch)
+			r.git("fetch", "--quiet", "origin")
+			r.git("remote", "set-head", "origin", "main")
+
+			args := append([]string{"--remote", "--accept-risk", "--output=json"}, test.options...)
+			stdout, stderr, err := r.runCLI(args...)
+			if err != nil || strings.Contains(stderr, "panic") {
+				t.Fatalf("%s failed: %v\n%s", strings.Join(args, " "), err, stderr)
+			}
+			if !strings.Contains(stdout, `"classification": "`+test.name+`"`) {
+				t.Fatalf("%s was not classified as %s:\n%s", branch, test.name, stdout)
+			}
+			if r.hasBranch(branch) {
+				t.Errorf("%s was not deleted:\n%s", branch, stderr)
+			}
+			if r.originHasBranch(branch) {
+				t.Errorf("%s was not deleted from origin:\n%s", branch, stderr)
+			}
+		})
+	}
+}
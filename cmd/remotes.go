@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// getGitConfig returns the last value of a config key ("" when unset)
+func getGitConfig(key string) (string, error) {
+	values, err := getGitConfigAll(key)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[len(values)-1], nil
+}
+
+func getRemotes() ([]string, error) {
+	return runCommandSplitLines("git", "remote")
+}
+
+// fetchRemote fetches remote with --prune, so that the base, and the upstreams
+// of branches (see getGoneUpstreams), are current before branches are analyzed
+func fetchRemote(remote string) error {
+	infof(T("fetching %s\n"), remote)
+	_, stderr, err := runCommandWithStderr("git", "fetch", "--quiet", "--prune", remote)
+	if err != nil && stderr != "" {
+		return errors.New(strings.TrimPrefix(strings.SplitN(stderr, "\n", 2)[0], "fatal: "))
+	}
+	return err
+}
+
+// Remotes describes a fork workflow, where branches are pushed to the fork
+// remote (e.g. origin) and merged into the upstream remote's default branch
+type Remotes struct {
+	Upstream string `json:"upstream"`
+	Fork     string `json:"fork"`
+}
+
+// getForkRemotes returns the upstream and fork remotes, or nil when the repo
+// isn't a fork (i.e. there is no upstream remote, or nothing else to push to).
+// The upstream remote is the one given, or else the remote named upstream; the
+// fork is the remote the base branch pushes to.
+func getForkRemotes(upstream, baseBranch string) (*Remotes, error) {
+	remotes, err := getRemotes()
+	if err != nil {
+		return nil, err
+	}
+	if upstream == "" {
+		upstream = "upstream"
+	}
+	known := map[string]bool{}
+	for _, r := range remotes {
+		known[strings.TrimSpace(r)] = true
+	}
+	if !known[upstream] {
+		return nil, nil
+	}
+
+	// the same precedence git push uses to pick a remote
+	fork := ""
+	for _, key := range []string{"branch." + baseBranch + ".pushRemote", "remote.pushDefault", "branch." + baseBranch + ".remote"} {
+		if fork, err = getGitConfig(key); err != nil {
+			return nil, err
+		}
+		if fork != "" && fork != upstream {
+			break
+		}
+	}
+	if fork == "" || fork == upstream {
+		fork = "origin"
+	}
+	if !known[fork] {
+		return nil, nil
+	}
+	return &Remotes{Upstream: upstream, Fork: fork}, nil
+}
+
+// getRemoteBase returns the name of the upstream remote's branch which the
+// base branch corresponds to, e.g. upstream/main, or "" when it hasn't been fetched
+func getRemoteBase(remote, baseBranch string) (string, error) {
+	defaultRefs, err := getRemoteDefaultBranches()
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range defaultRefs {
+		if strings.HasPrefix(ref, remotePrefix+remote+"/") {
+			return strings.TrimPrefix(ref, remotePrefix), nil
+		}
+	}
+	if _, err := getGitRevParse(remotePrefix + remote + "/" + baseBranch); err == nil {
+		return remote + "/" + baseBranch, nil
+	}
+	return "", nil
+}
+
+// isOnFork returns true when the upstream ref is a branch on the fork remote
+// which may be deleted there, i.e. which isn't protected under its remote name
+func (rs *runState) isOnFork(upstream string) bool {
+	if !strings.HasPrefix(upstream, remotePrefix+rs.remotes.Fork+"/") {
+		return false
+	}
+	_, protected := rs.protection.reason(remoteBranchName(upstream))
+	return !protected
+}
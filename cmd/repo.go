@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// exit codes; git itself exits 128 on fatal errors, so we never pass that through
+const (
+	exitFailure     = 1 // the run failed part way through
+	exitUsage       = 2 // invalid options or command
+	exitGitMissing  = 3 // git could not be run
+	exitNotRepo     = 4 // not inside a git repository
+	exitNoHead      = 5 // HEAD is missing, unborn, or corrupt
+	exitCorruptRefs = 6 // branch refs are broken or point at missing objects
+	exitIncomplete  = 7 // the run finished, but some branches could not be analyzed or deleted
+)
+
+func dieWithCode(code int, msg string, args ...interface{}) {
+	errorf(msg, args...)
+	os.Exit(code)
+}
+
+// runCommandWithStderr is like runCommandTrimmedOutput, but also returns stderr
+// so the reason for a failure can be reported
+func runCommandWithStderr(args ...string) (string, string, error) {
+	debugf("running %s", strings.Join(args, " "))
+	acquireProc()
+	defer releaseProc()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return strings.TrimSpace(stdout.String()), strings.TrimSpace(stderr.String()), err
+}
+
+// isExitStatus returns true when err is a command which exited with code
+func isExitStatus(err error, code int) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == code
+}
+
+// describeError adds what a failed command printed to stderr (when captured) to err
+func describeError(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Sprintf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err.Error()
+}
+
+// checkRepository verifies up front that we are in a healthy git repository,
+// so that problems are reported with an actionable message rather than as an
+// opaque subprocess failure part way through a run.
+func checkRepository(needHead bool) {
+	if _, err := exec.LookPath("git"); err != nil {
+		dieWithCode(exitGitMissing, T("git was not found in PATH; install git or add it to PATH\n"))
+	}
+
+	_, stderr, err := runCommandWithStderr("git", "rev-parse", "--git-dir")
+	if err != nil {
+		dieWithCode(exitNotRepo, T("not a git repository: run this from inside a git checkout (%s)\n"), stderr)
+	}
+
+	if needHead {
+		if _, _, err := runCommandWithStderr("git", "rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err != nil {
+			dieWithCode(exitNoHead, T("HEAD does not point at a commit: the repository is empty or HEAD is corrupt (check .git/HEAD)\n"))
+		}
+	}
+
+	// %(objecttype) forces each ref's object to be read, so missing objects are detected too
+	_, stderr, err = runCommandWithStderr("git", "for-each-ref", "--format=%(refname) %(objecttype)", branchPrefix)
+	broken := []string{}
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "warning: ignoring broken ref ") {
+			broken = append(broken, strings.TrimPrefix(line, "warning: ignoring broken ref "))
+		} else if err != nil && strings.HasPrefix(line, "fatal: ") {
+			broken = append(broken, strings.TrimPrefix(line, "fatal: "))
+		}
+	}
+	if err != nil && len(broken) == 0 {
+		broken = append(broken, fmt.Sprintf("git for-each-ref failed: %v", err))
+	}
+	if len(broken) > 0 {
+		dieWithCode(exitCorruptRefs, T("found broken branch refs; repair or delete them (e.g. git update-ref -d <ref>) and run git fsck:\n  %s\n"), strings.Join(broken, "\n  "))
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// branch classifications
+const (
+	classMerged            = "merged"             // the branch tip is part of the base history
+	classTrackingDuplicate = "tracking-duplicate" // the branch points exactly at the base tip
+	classMatched           = "matched"            // a base commit has identical contents (e.g. squash or rebase merged)
+	classRevert            = "revert"             // a revert branch (see isRevertBranch) which matched a revert on the base
+	classPotential         = "potential"          // a base commit is similar, but needs a human to review it
+	classUnmerged          = "unmerged"
+	classSkipped           = "skipped"               // protected from analysis (see Reason)
+	classDeferred          = "deferred"              // not analyzed this run due to --limit or --time-budget
+	classInUse             = "checked-out-elsewhere" // another worktree or symbolic ref uses the branch (see Reason)
+	classRebased           = "rebased"               // rebased onto an upstream which is still active
+	classEmpty             = "empty"                 // the branch only contains empty commits
+	classUpstreamGone      = "upstream-gone"         // the upstream branch was deleted, e.g. after its pull request merged (see Reason)
+	classUnknown           = "unknown"               // not merged by ancestry, and not analyzed further due to --quick
+	classDuplicate         = "duplicate"             // has the same commit or diff as another branch which is kept (see Reason)
+	classBackup            = "backup"                // named like a backup (e.g. foo-old), and contained in another branch (see Reason)
+	classMergedIntoDeleted = "merged-into-deleted"   // merged into a branch which was deleted once it landed (see Reason)
+	classTooComplex        = "too-complex"           // has more than --max-commits unmerged commits, too many to analyze reliably
+	classError             = "error"                 // could not be analyzed due to an unexpected git error (see Reason)
+	classUpstreamed        = "upstreamed"            // with fork-sync, an unmerged branch whose commits all have an equivalent upstream (see isUpstreamed)
+)
+
+// reportJSON is set when the report is written as JSON, in which case the
+// human-readable report lines are suppressed
+var reportJSON bool
+
+// isTextReport returns true when the human-readable report is written, i.e.
+// when the report isn't written as JSON, a table, or with --format
+func isTextReport() bool {
+	return !reportJSON && !reportTable && reportTemplate == nil
+}
+
+// reportf writes a line of the human-readable report to stdout
+func reportf(format string, args ...interface{}) {
+	if !isTextReport() {
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+type BranchReport struct {
+	Branch          string `json:"branch"`
+	Classification  string `json:"classification"`
+	Reason          string `json:"reason,omitempty"`
+	Group           string `json:"group,omitempty"`
+	Deleted         bool   `json:"deleted"`
+	RemoteDeleted   bool   `json:"remoteDeleted,omitempty"` // also deleted from the remote --remote names
+	*PotentialMerge `json:",omitempty"`
+	DiskUsage       int64        `json:"diskUsage,omitempty"`   // bytes of objects only this branch retains
+	Owners          []string     `json:"owners,omitempty"`      // CODEOWNERS owners of the files the branch changes
+	DeleteError     string       `json:"deleteError,omitempty"` // why deleting the branch failed
+	PullRequest     *PullRequest `json:"pullRequest,omitempty"` // the pull request the matched commit was merged from
+	Risks           []string     `json:"risks,omitempty"`       // why deleting the branch is riskier than its classification suggests; such branches are only deleted with --accept-risk or when approved interactively
+	Receipt         *Receipt     `json:"receipt,omitempty"`     // set once the branch is deleted
+	Directive       string       `json:"directive,omitempty"`   // "delete" when a trailer of the branch's tip opts it in to deletion once merged (see getBranchDirectives)
+}
+
+// tipSha returns the branch's tip as analyzed, or, for reports classified
+// without analyzing its history (e.g. upstream-gone, or unknown with --quick),
+// where it points now
+func (b *BranchReport) tipSha() (string, error) {
+	if b.PotentialMerge != nil && b.TipSha != "" {
+		return b.TipSha, nil
+	}
+	return getGitRevParse(branchRef(b.Branch))
+}
+
+// reportSchemaVersion is bumped whenever a report field is removed, renamed, or
+// changes meaning; adding fields doesn't change it
+const reportSchemaVersion = 1
+
+// reportSchema is the JSON schema of Report, printed by --schema
+//
+//go:embed report.schema.json
+var reportSchema []byte
+
+type Report struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Time          time.Time          `json:"time"`
+	Base          string             `json:"base"`
+	Branches      []*BranchReport    `json:"branches"`
+	Sample        *SampleSummary     `json:"sample,omitempty"`
+	Groups        []*GroupSummary    `json:"groups,omitempty"`
+	Audit         []string           `json:"audit,omitempty"`       // repository states which make the analysis unreliable
+	Reclaimable   int64              `json:"reclaimable,omitempty"` // bytes retained only by the deleted branches, which git gc prunes once they are unreachable
+	GC            *GCSummary         `json:"gc,omitempty"`
+	Alternates    []string           `json:"alternates,omitempty"`    // the object directories objects are borrowed from, which aren't counted in disk usage
+	Scope         string             `json:"scope,omitempty"`         // the directory the run was limited to with --scope
+	BaseRewritten bool               `json:"baseRewritten,omitempty"` // the base was rewritten since the last run, which discarded the state derived from its old history
+	Submodules    []*SubmoduleReport `json:"submodules,omitempty"`    // with --recurse-submodules
+}
+
+func (r *Report) add(branch, classification string, potentialMerge *PotentialMerge) *BranchReport {
+	branchReport := &BranchReport{
+		Branch:         branch,
+		Classification: classification,
+		PotentialMerge: potentialMerge,
+	}
+	r.Branches = append(r.Branches, branchReport)
+	return branchReport
+}
+
+// classify decides what a potential merge means given the score thresholds
+func classify(potentialMerged *PotentialMerge, progOpts *opts) string {
+	if potentialMerged.AtBaseTip {
+		return classTrackingDuplicate
+	}
+	if potentialMerged.Merged {
+		return classMerged
+	}
+	if potentialMerged.Empty {
+		return classEmpty
+	}
+	if potentialMerged.RebasedUpstream != "" {
+		return classRebased
+	}
+	matched := classMatched
+	if potentialMerged.Revert {
+		matched = classRevert
+	}
+	if potentialMerged.TreeIdentical {
+		// the branch's contents are exactly those of a base commit, regardless of how the diffs compare
+		return matched
+	}
+	if potentialMerged.SubjectScore > progOpts.MinSubjectScore && potentialMerged.DiffScore > progOpts.MinDiffScore {
+		perfectDiffMatch := bool(potentialMerged.DiffScore == 1.0 && potentialMerged.DiffSize > 10)
+		if perfectDiffMatch {
+			return matched
+		}
+		return classPotential
+	}
+	return classUnmerged
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false) // keeps shell commands such as diffCmd readable
+	return enc.Encode(v)
+}
+
+func readReport(path string) (*Report, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(contents, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+func isCandidate(classification string) bool {
+	switch classification {
+	case classMerged, classTrackingDuplicate, classEmpty, classMatched, classRevert, classPotential, classUpstreamGone, classDuplicate, classBackup, classMergedIntoDeleted, classUpstreamed:
+		return true
+	}
+	return false
+}
+
+// isDeletable returns true when cleanup deletes branches of the classification
+// (its risks permitting), rather than only reporting them
+func isDeletable(classification string, progOpts *opts) bool {
+	switch classification {
+	case classMerged, classMatched, classRevert, classMergedIntoDeleted, classUpstreamed:
+		return true
+	case classTrackingDuplicate:
+		return progOpts.DeleteTrackingDuplicates
+	case classEmpty:
+		return progOpts.DeleteEmpty
+	case classBackup:
+		return progOpts.CleanBackups
+	case classDuplicate:
+		return progOpts.DeleteDuplicates
+	case classUpstreamGone:
+		return progOpts.DeleteUpstreamGone
+	}
+	return false
+}
+
+// getCandidates returns the reports of the branches which could be deleted
+func getCandidates(report *Report) []*BranchReport {
+	candidates := []*BranchReport{}
+	for _, b := range report.Branches {
+		if isCandidate(b.Classification) {
+			candidates = append(candidates, b)
+		}
+	}
+	return candidates
+}
+
+type ReportChange struct {
+	Branch string `json:"branch"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// ReportDiff describes what changed between two runs' JSON reports
+type ReportDiff struct {
+	NewCandidates []*ReportChange `json:"newCandidates"` // branches which became candidates for deletion
+	Deleted       []*ReportChange `json:"deleted"`       // branches deleted by the new run, or gone since the old run
+	Changed       []*ReportChange `json:"changed"`       // all other classification changes
+}
+
+func compareReports(oldReport, newReport *Report) *ReportDiff {
+	diff := &ReportDiff{
+		NewCandidates: []*ReportChange{},
+		Deleted:       []*ReportChange{},
+		Changed:       []*ReportChange{},
+	}
+	oldBranches := map[string]*BranchReport{}
+	for _, b := range oldReport.Branches {
+		oldBranches[b.Branch] = b
+	}
+	newBranches := map[string]bool{}
+	for _, b := range newReport.Branches {
+		newBranches[b.Branch] = true
+		change := &ReportChange{Branch: b.Branch, New: b.Classification}
+		old, ok := oldBranches[b.Branch]
+		if ok {
+			change.Old = old.Classification
+		}
+		switch {
+		case b.Deleted:
+			diff.Deleted = append(diff.Deleted, change)
+		case isCandidate(b.Classification) && (!ok || !isCandidate(old.Classification)):
+			diff.NewCandidates = append(diff.NewCandidates, change)
+		case ok && old.Classification != b.Classification:
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for _, b := range oldReport.Branches {
+		if !newBranches[b.Branch] && !b.Deleted {
+			diff.Deleted = append(diff.Deleted, &ReportChange{Branch: b.Branch, Old: b.Classification})
+		}
+	}
+	return diff
+}
+
+// diffReports prints the changes between two reports written with --output=json
+func diffReports(oldPath, newPath string) error {
+	oldReport, err := readReport(oldPath)
+	if err != nil {
+		return err
+	}
+	newReport, err := readReport(newPath)
+	if err != nil {
+		return err
+	}
+	diff := compareReports(oldReport, newReport)
+	if reportJSON {
+		return writeJSON(os.Stdout, diff)
+	}
+
+	fmt.Print(T("new candidates:\n"))
+	for _, c := range diff.NewCandidates {
+		fmt.Printf("  %s (%s)\n", c.Branch, c.New)
+	}
+	fmt.Print(T("deleted:\n"))
+	for _, c := range diff.Deleted {
+		fmt.Printf("  %s\n", c.Branch)
+	}
+	fmt.Print(T("changed classification:\n"))
+	for _, c := range diff.Changed {
+		fmt.Printf("  %s: %s -> %s\n", c.Branch, c.Old, c.New)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// This reverts commit 0123abcd..., as git revert writes in the message
+var revertedCommitRegexp = regexp.MustCompile(`(?m)^This reverts commit ([0-9a-f]{7,64})`)
+
+// isRevertSubject returns true for the subjects git revert (and forges' revert
+// buttons) give commits, e.g. Revert "Add the widget"
+func isRevertSubject(subject string) bool {
+	return strings.HasPrefix(subject, "Revert ")
+}
+
+// getRevertedCommit returns the commit the commit's message says it reverts, if any
+func getRevertedCommit(commit string) (string, error) {
+	message, err := runCommandTrimmedOutput("git", "--no-pager", "show", "--format=format:%B", "-s", commit, "--")
+	if err != nil {
+		return "", err
+	}
+	if m := revertedCommitRegexp.FindStringSubmatch(message); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+// isRevertBranch returns true when the branch reverts an earlier change: it is
+// named like a forge's revert branches (e.g. revert-123-add-widget), or its
+// only commit is a revert. Such a branch is the inverse of the change it
+// reverts, so it has to be matched against the revert on the base instead,
+// which otherwise loses to the change itself as the closer subject.
+func isRevertBranch(branch string, branchCommits []string, tip *CommitDiff) bool {
+	if strings.HasPrefix(path.Base(branch), "revert-") {
+		return true
+	}
+	return len(branchCommits) == 1 && isRevertSubject(tip.Subject)
+}
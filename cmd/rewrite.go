@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+const baseTipsFileName = "base-tips.json"
+
+// loadBaseTips returns the tip each base was at when last analyzed against
+func loadBaseTips() (map[string]string, error) {
+	path, err := getStateFilePath(baseTipsFileName)
+	if err != nil {
+		return nil, err
+	}
+	tips := map[string]string{}
+	if err := readJSONFile(path, &tips); err != nil {
+		return nil, err
+	}
+	return tips, nil
+}
+
+// saveBaseTip records the tip the base was analyzed at, for detectBaseRewrite
+func saveBaseTip(baseName, baseRev string) error {
+	tips, err := loadBaseTips()
+	if err != nil {
+		return err
+	}
+	if tips[baseName] == baseRev {
+		return nil
+	}
+	tips[baseName] = baseRev
+	path, err := getStateFilePath(baseTipsFileName)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(path, tips)
+}
+
+// detectBaseRewrite returns true when the base was rewritten (e.g. force-pushed)
+// since it was last analyzed against, i.e. when its previous tip is no longer
+// part of it. Whatever was worked out from the old history may then be void,
+// so the state derived from it is discarded: where a --limit run would resume,
+// the queue of pending reviews, and the commit cache (whose diffs, though
+// still correct, are mostly of commits which will never be compared again).
+// Review decisions are kept, since they were made about the branches' tips.
+func (rs *runState) detectBaseRewrite() (bool, error) {
+	tips, err := loadBaseTips()
+	if err != nil {
+		return false, err
+	}
+	previous := tips[rs.baseName]
+	if previous == "" || previous == rs.baseRev || isAncestor(previous, rs.baseRev) {
+		return false, nil
+	}
+
+	warnf(T("WARNING: %s was rewritten since the last run (%s is no longer part of it); discarding the progress, queue, and cache computed from its old history\n"), rs.baseName, previous)
+	for _, name := range []string{progressFileName, queueFileName, commitCacheFileName} {
+		path, err := getStateFilePath(name)
+		if err != nil {
+			return true, err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return true, err
+		}
+	}
+	CommitDiffCache = nil
+	return true, nil
+}
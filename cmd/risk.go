@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smallDiffSize is the size (in bytes of diff output) below which a change is
+// only a line or two, which unrelated commits easily share
+const smallDiffSize = 256
+
+func getCommitTime(commit string) (time.Time, error) {
+	out, err := runCommandTrimmedOutput("git", "--no-pager", "show", "-s", "--format=%ct", commit, "--")
+	if err != nil {
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// assessRisks lists what makes deleting a branch riskier than its
+// classification suggests, even when it matched perfectly: work committed
+// after the commit it matched, an upstream with commits the branch lacks, or a
+// change so small that the match may be a coincidence
+func assessRisks(b *BranchReport, upstreams map[string]string) ([]string, error) {
+	risks := []string{}
+	ref := branchRef(b.Branch)
+
+	if upstream := upstreams[b.Branch]; upstream != "" {
+		if _, err := getGitRevParse(upstream); err == nil {
+			ahead, err := runCommandTrimmedOutput("git", "rev-list", "--count", upstream, "--not", ref, "--")
+			if err != nil {
+				return nil, err
+			}
+			if ahead != "0" {
+				risks = append(risks, fmt.Sprintf("its upstream %s has %s commits which it doesn't", strings.TrimPrefix(upstream, remotePrefix), ahead))
+			}
+		}
+	}
+
+	if b.Classification == classMatched || b.Classification == classRevert {
+		tipTime, err := getCommitTime(ref)
+		if err != nil {
+			return nil, err
+		}
+		matchedTime, err := getCommitTime(b.MatchedSha)
+		if err != nil {
+			return nil, err
+		}
+		if tipTime.After(matchedTime) {
+			risks = append(risks, "its tip was committed after the commit it matched")
+		}
+		if b.DiffSize < smallDiffSize && !b.TreeIdentical {
+			risks = append(risks, fmt.Sprintf("its diff is only %d bytes", b.DiffSize))
+		}
+	}
+	return risks, nil
+}
+
+// addRisks assesses the risks of deleting each branch which would be deleted
+func addRisks(report *Report) error {
+	upstreams, err := getBranchUpstreams()
+	if err != nil {
+		return err
+	}
+	for _, b := range report.Branches {
+		if !isCandidate(b.Classification) || b.Classification == classPotential {
+			continue // potential merges are never deleted without a review anyway
+		}
+		if b.Risks, err = assessRisks(b, upstreams); err != nil {
+			return err
+		}
+	}
+	return nil
+}
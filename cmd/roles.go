@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+)
+
+// The operations branch-cleanup.allow may permit, each implying those before it
+const (
+	opReportOnly   = "report-only"   // only report, never change anything
+	opLocalDelete  = "local-delete"  // delete local branches (and clean up after them)
+	opRemoteDelete = "remote-delete" // also delete, or push, branches on remotes
+)
+
+var operationLevels = map[string]int{opReportOnly: 0, opLocalDelete: 1, opRemoteDelete: 2}
+
+// iAmMaintainer is set by --i-am-maintainer, permitting every operation
+// regardless of branch-cleanup.allow
+var iAmMaintainer bool
+
+var allowedOperation *string // branch-cleanup.allow, once read
+
+// getAllowedOperation returns branch-cleanup.allow, which a shared clone sets
+// so that casual contributors running the tool there can't, say, push branch
+// deletions; "" when unset, permitting everything
+func getAllowedOperation() (string, error) {
+	if allowedOperation != nil {
+		return *allowedOperation, nil
+	}
+	allowed, err := getGitConfig("branch-cleanup.allow")
+	if err != nil {
+		return "", err
+	}
+	if _, ok := operationLevels[allowed]; !ok && allowed != "" {
+		return "", fmt.Errorf("invalid branch-cleanup.allow %q; expected %s, %s, or %s", allowed, opReportOnly, opLocalDelete, opRemoteDelete)
+	}
+	allowedOperation = &allowed
+	return allowed, nil
+}
+
+// checkAllowed fails unless the operation may be run, i.e. the repository
+// isn't read-only (see readOnly), and branch-cleanup.allow permits it or
+// --i-am-maintainer was given
+func checkAllowed(op string) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if iAmMaintainer {
+		return nil
+	}
+	allowed, err := getAllowedOperation()
+	if err != nil {
+		return err
+	}
+	if allowed != "" && operationLevels[op] > operationLevels[allowed] {
+		return fmt.Errorf("branch-cleanup.allow is %s in this repository, which doesn't permit %s without --i-am-maintainer", allowed, op)
+	}
+	return nil
+}
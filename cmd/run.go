@@ -0,0 +1,1013 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// runState holds what is known about the repository and base for a run
+type runState struct {
+	progOpts       *opts
+	stats          *RunStats
+	branches       []string
+	currentBranch  string
+	baseName       string // as given by the user, e.g. main or v2.3.0
+	baseRev        string // the sha baseName resolved to
+	jjColocated    bool
+	removeBranch   func(receipt *Receipt) error // deletes the receipt's branch, recording it in the deletion log (see deletionLogRef) and the journal
+	journal        *JournalRun                  // the receipts of this run's deletions
+	remotes        *Remotes                     // set in a fork workflow
+	protection     *ProtectionRules
+	scope          string // the directory --scope limits the run to, relative to the top of the worktree
+	deleteDisabled error  // why nothing may be deleted this run (see checkAllowed), if so
+	uncapped       bool   // set while deleting the interactively approved branches, which --max-delete doesn't limit
+}
+
+// errMaxDelete is returned by removeBranch once --max-delete branches were deleted
+var errMaxDelete = errors.New("--max-delete was reached")
+
+// newRunState checks the repository and resolves the base to compare branches against
+func newRunState(progOpts *opts) *runState {
+	checkRepository(progOpts.Base == "")
+	rs := &runState{progOpts: progOpts, stats: &RunStats{Time: time.Now()}}
+
+	if progOpts.Fetch != "" {
+		if err := checkWritable(); err != nil {
+			warnf(T("not fetching %s: %v\n"), progOpts.Fetch, err)
+		} else if err := fetchRemote(progOpts.Fetch); err != nil {
+			die(T("failed to fetch %s: %v\n"), progOpts.Fetch, err)
+		}
+	}
+
+	var err error
+	branches, err := getBranches()
+	if err != nil {
+		die(T("failed to get branches: %v\n"), err)
+	}
+	rs.branches = []string{}
+	for _, branch := range branches {
+		if !isArchivedBranch(branch) {
+			rs.branches = append(rs.branches, branch)
+		}
+	}
+
+	jjColocated := isJujutsuColocated()
+
+	currentBranch, err := getCurrentBranch()
+	if err != nil && jjColocated {
+		currentBranch, err = getJujutsuBaseBranch(rs.branches)
+	}
+
+	baseName := progOpts.Base
+	if baseName == "" {
+		if err != nil {
+			die(T("failed to get current branch: %v\n"), err)
+		}
+		switch currentBranch {
+		case "main", "master", "trunk":
+			break
+		default:
+			die(T("current branch is %s; expected main, master, or trunk"), currentBranch)
+		}
+		baseName = currentBranch
+
+		// in a fork workflow, branches are merged upstream, and the fork's base branch may lag behind
+		rs.remotes, err = getForkRemotes(progOpts.UpstreamRemote, baseName)
+		if err != nil {
+			die(T("failed to list remotes: %v\n"), err)
+		}
+		if rs.remotes == nil && progOpts.UpstreamRemote != "" {
+			dieWithCode(exitUsage, T("remote %s does not exist\n"), progOpts.UpstreamRemote)
+		}
+		if rs.remotes != nil {
+			remoteBase, err := getRemoteBase(rs.remotes.Upstream, baseName)
+			if err != nil {
+				die(T("failed to resolve the default branch of %s: %v\n"), rs.remotes.Upstream, err)
+			}
+			if remoteBase != "" {
+				infof(T("comparing against %s, since %s is a fork of %s\n"), remoteBase, rs.remotes.Fork, rs.remotes.Upstream)
+				baseName = remoteBase
+			}
+		}
+	}
+	baseRev, err := resolveBase(baseName)
+	if err != nil {
+		die(T("failed to resolve base %s: %v\n"), baseName, err)
+	}
+	rs.currentBranch = currentBranch
+	rs.baseName = baseName
+	rs.baseRev = baseRev
+	rs.jjColocated = jjColocated
+
+	if progOpts.Scope != "" {
+		rs.scope, err = resolveScope(progOpts.Scope)
+		if err != nil {
+			dieWithCode(exitUsage, T("invalid --scope: %v\n"), err)
+		}
+	}
+
+	rs.stats.Branches = len(rs.branches)
+	rs.deleteDisabled = checkAllowed(opLocalDelete)
+	if rs.deleteDisabled == nil && progOpts.DryRun {
+		rs.deleteDisabled = errors.New("--dry-run was given")
+	}
+	if rs.deleteDisabled != nil {
+		infof(T("nothing will be deleted: %v\n"), rs.deleteDisabled)
+	}
+
+	if !progOpts.UseReplaceObjects {
+		if err := loadCommitCache(); err != nil {
+			warnf(T("ignoring the commit cache: %v\n"), err)
+		}
+	}
+
+	remove := deleteBranch
+	if jjColocated && progOpts.JJForget {
+		remove = forgetJujutsuBookmark
+	}
+	if progOpts.ArchiveRename {
+		if progOpts.Archive != "" {
+			dieWithCode(exitUsage, T("--archive can't be combined with --archive-rename\n"))
+		}
+		remove = func(branchName string) error {
+			return archiveBranch(branchName, archivedBranchName(branchName, rs.stats.Time))
+		}
+	}
+	if progOpts.Archive != "" {
+		deleteArchived := remove
+		remove = func(branchName string) error {
+			if err := archiveTip(branchName, progOpts.Archive == "tag"); err != nil {
+				return err
+			}
+			return deleteArchived(branchName)
+		}
+	}
+	rs.journal = &JournalRun{Time: rs.stats.Time, Base: rs.baseName, Receipts: []*Receipt{}}
+	rs.removeBranch = func(receipt *Receipt) error {
+		if rs.deleteDisabled != nil {
+			return rs.deleteDisabled
+		}
+		// every way of deleting (cleanup, fork-sync, resolve, the stdio server) counts towards the limit
+		if progOpts.MaxDelete > 0 && len(rs.journal.Receipts) >= progOpts.MaxDelete && !rs.uncapped {
+			return errMaxDelete
+		}
+		tip, err := getGitRevParse(branchRef(receipt.Branch))
+		if err != nil {
+			return err
+		}
+		if err := remove(receipt.Branch); err != nil {
+			return err
+		}
+		if err := logDeletion(receipt.Branch, tip, receipt.Reason); err != nil {
+			warnf(T("failed to log the deletion of %s: %v\n"), receipt.Branch, err)
+		}
+		receipt.TipSha = tip
+		receipt.DeletedAt = time.Now()
+		receipt.Restore = fmt.Sprintf("git branch %s %s", shellQuote(receipt.Branch), tip)
+		if progOpts.ArchiveRename {
+			receipt.ArchivedAs = archivedBranchName(receipt.Branch, rs.stats.Time)
+			receipt.Restore = fmt.Sprintf("git branch -m %s %s", shellQuote(receipt.ArchivedAs), shellQuote(receipt.Branch))
+		}
+		rs.journal.Receipts = append(rs.journal.Receipts, receipt)
+		if err := saveJournalRun(rs.journal); err != nil {
+			warnf(T("failed to write the journal: %v\n"), err)
+		}
+		return nil
+	}
+	return rs
+}
+
+// analyzeOnly analyzes a single branch without deleting anything, which is
+// quick enough for editors to show whether the branches they list are merged
+func (rs *runState) analyzeOnly(branch string) (*Report, error) {
+	for _, b := range rs.branches {
+		if b == branch {
+			branches := rs.branches
+			defer func() { rs.branches = branches }()
+			rs.branches = []string{branch}
+			return rs.analyze(), nil
+		}
+	}
+	return nil, fmt.Errorf("no such branch")
+}
+
+// analyze classifies every local branch against the base, without changing anything
+func (rs *runState) analyze() *Report {
+	progOpts := rs.progOpts
+
+	if err := rs.checkBaseLag(); err != nil {
+		die(T("refusing to analyze against a stale base: %v\n"), err)
+	}
+	rewritten, err := rs.detectBaseRewrite()
+	if err != nil {
+		die(T("failed to discard state from before %s was rewritten: %v\n"), rs.baseName, err)
+	}
+
+	// with --base, the checked out branch is analyzed like any other (see switchAway)
+	protectedCurrent := rs.currentBranch
+	if progOpts.Base != "" && !rs.jjColocated {
+		protectedCurrent = ""
+	}
+
+	rs.protection, err = getProtectionRules(protectedCurrent)
+	if err != nil {
+		die(T("failed to determine protected branches: %v\n"), err)
+	}
+	protectedBranches := rs.protection.protectedBranches(rs.branches)
+	if rs.baseName != rs.currentBranch {
+		protectedBranches[rs.baseName] = "base" // only matters when the base is a branch
+	}
+
+	inUseBranches, err := getCheckedOutElsewhere(rs.currentBranch)
+	if err != nil {
+		die(T("failed to list worktrees: %v\n"), err)
+	}
+
+	stackBranches := map[string]string{}
+	if !progOpts.IgnoreStackTools {
+		stackBranches, err = getStackToolBranches(rs.branches)
+		if err != nil {
+			die(T("failed to detect stacked-diff tools: %v\n"), err)
+		}
+	}
+
+	jjBookmarks := map[string]bool{}
+	if rs.jjColocated {
+		jjBookmarks, err = getJujutsuActiveBookmarks()
+		if err != nil {
+			die(T("failed to list jj bookmarks: %v\n"), err)
+		}
+	}
+
+	rejected, err := loadRejections()
+	if err != nil {
+		die(T("failed to load review rejections: %v\n"), err)
+	}
+	if err := addSharedRejections(rejected); err != nil {
+		die(T("failed to read shared review decisions: %v\n"), err)
+	}
+
+	directives, err := getBranchDirectives()
+	if err != nil {
+		die(T("failed to read the branches' cleanup trailers: %v\n"), err)
+	}
+
+	// owners are only worked out when they are used, since it means diffing every branch
+	var codeowners Codeowners
+	if len(progOpts.OwnedBy) > 0 || progOpts.GroupBy == "owner" || reportJSON {
+		codeowners, err = loadCodeowners(rs.baseRev)
+		if err != nil {
+			die(T("failed to read CODEOWNERS: %v\n"), err)
+		}
+		if codeowners == nil && len(progOpts.OwnedBy) > 0 {
+			die(T("--owned-by needs a CODEOWNERS file, but there is none in %s\n"), rs.baseName)
+		}
+	}
+	owners := map[string][]string{}
+
+	report := &Report{SchemaVersion: reportSchemaVersion, Time: rs.stats.Time, Base: rs.baseName, Scope: rs.scope, BaseRewritten: rewritten}
+	if rs.scope != "" {
+		infof(T("only analyzing branches which change files under %s/\n"), rs.scope)
+	}
+	eligible := []string{}
+	for _, branch := range rs.branches {
+		if branch == protectedCurrent {
+			continue // dont try to delete the current branch (e.g. main)
+		}
+		if reason, ok := protectedBranches[branch]; ok {
+			infof(T("skipping %s: protected (%s)\n"), branch, reason)
+			report.add(branch, classSkipped, nil).Reason = fmt.Sprintf(T("protected: %s"), reason)
+			continue
+		}
+		if where, ok := inUseBranches[branch]; ok {
+			report.add(branch, classInUse, nil).Reason = where
+			continue
+		}
+		if tool, ok := stackBranches[branch]; ok {
+			infof(T("skipping %s: tracked by %s\n"), branch, tool)
+			report.add(branch, classSkipped, nil).Reason = fmt.Sprintf(T("tracked by %s"), tool)
+			continue
+		}
+		if jjBookmarks[branch] {
+			infof(T("skipping %s: active jj bookmark\n"), branch)
+			report.add(branch, classSkipped, nil).Reason = T("active jj bookmark")
+			continue
+		}
+		if tip, ok := rejected[branch]; ok {
+			if sha, err := getGitRevParse(branchRef(branch)); err == nil && sha == tip {
+				infof(T("skipping %s: rejected in review\n"), branch)
+				report.add(branch, classSkipped, nil).Reason = T("rejected in review")
+				continue
+			}
+		}
+		if directives[branch] == directiveKeep {
+			infof(T("skipping %s: its tip asks to keep it\n"), branch)
+			report.add(branch, classSkipped, nil).Reason = T("kept by a trailer of its tip")
+			continue
+		}
+		if rs.scope != "" {
+			inScope, err := touchesPaths(rs.baseRev, branch, scopePathspecs(rs.scope))
+			if err != nil {
+				die(T("failed to check which paths %s touches: %v\n"), branch, err)
+			}
+			if !inScope {
+				debugf("ignoring %s: it doesn't change anything under %s", branch, rs.scope)
+				continue
+			}
+			sparsePathspecs[branch] = scopePathspecs(rs.scope)
+		}
+		if len(progOpts.Touches) > 0 {
+			touches, err := touchesPaths(rs.baseRev, branch, progOpts.Touches)
+			if err != nil {
+				die(T("failed to check which paths %s touches: %v\n"), branch, err)
+			}
+			if !touches {
+				debugf("ignoring %s: it doesn't touch %s", branch, strings.Join(progOpts.Touches, " "))
+				continue
+			}
+			sparsePathspecs[branch] = progOpts.Touches
+		}
+		if codeowners != nil {
+			var ownedFiles []string
+			owners[branch], ownedFiles, err = getBranchOwners(codeowners, rs.baseRev, branch, progOpts.OwnedBy)
+			if err != nil {
+				die(T("failed to find the owners of %s: %v\n"), branch, err)
+			}
+			if len(progOpts.OwnedBy) > 0 {
+				if len(ownedFiles) == 0 {
+					debugf("ignoring %s: it isn't owned by %s", branch, strings.Join(progOpts.OwnedBy, " "))
+					continue
+				}
+				sparsePathspecs[branch] = literalPathspecs(ownedFiles)
+			}
+		}
+		eligible = append(eligible, branch)
+	}
+
+	toAnalyze := eligible
+	if progOpts.Sample > 0 {
+		toAnalyze, err = sampleBranches(eligible, progOpts.Sample, progOpts.SampleOrder)
+		if err != nil {
+			die(T("failed to sample branches: %v\n"), err)
+		}
+		report.Sample = &SampleSummary{Eligible: len(eligible), Analyzed: len(toAnalyze)}
+	}
+
+	budgeted := progOpts.Limit > 0 || progOpts.TimeBudget > 0
+	var progress *Progress
+	if budgeted {
+		progress, err = loadProgress()
+		if err != nil {
+			die(T("failed to load progress: %v\n"), err)
+		}
+		toAnalyze = resumeAfter(toAnalyze, progress.LastAnalyzed)
+		progress.LastAnalyzed = ""
+	}
+
+	// a branch whose upstream was deleted (e.g. by the forge, once its pull request was squash merged) likely landed,
+	// even when its diff no longer matches anything
+	goneUpstreams, err := getGoneUpstreams()
+	if err != nil {
+		die(T("failed to get branch upstreams: %v\n"), err)
+	}
+	addUpstreamGone := func(branch string) {
+		report.add(branch, classUpstreamGone, nil).Reason = fmt.Sprintf(T("upstream %s is gone"), strings.TrimPrefix(goneUpstreams[branch], remotePrefix))
+		rs.stats.Candidates++
+	}
+
+	// a branch which can't be analyzed is reported as such, rather than as unmerged
+	failed := func(branch string, err error) {
+		if progOpts.Strict {
+			die(T("failed to analyze %s: %v\n"), branch, describeError(err))
+		}
+		warnf(T("ignoring %s due to: %s\n"), branch, describeError(err))
+		report.add(branch, classError, nil).Reason = describeError(err)
+		rs.stats.Errors++
+	}
+
+	for i, branch := range toAnalyze {
+		// always analyze at least one branch, so every run makes progress
+		if budgeted && i > 0 && ((progOpts.Limit > 0 && i >= progOpts.Limit) || (progOpts.TimeBudget > 0 && time.Since(rs.stats.Time) > progOpts.TimeBudget)) {
+			// out of budget, the remaining branches are picked up by the next run
+			for _, deferred := range toAnalyze[i:] {
+				report.add(deferred, classDeferred, nil)
+			}
+			progress.LastAnalyzed = toAnalyze[i-1]
+			infof(T("stopped after analyzing %d of %d branches; the next run resumes after %s\n"), i, len(toAnalyze), progress.LastAnalyzed)
+			break
+		}
+		debugf("analyzing %s against %s", branch, rs.baseName)
+		rs.stats.Analyzed++
+		if progOpts.Quick {
+			classification, potentialMerged, err := quickClassify(rs.baseRev, branch)
+			if err != nil {
+				failed(branch, err)
+				continue
+			}
+			if _, ok := goneUpstreams[branch]; ok && classification == classUnknown {
+				addUpstreamGone(branch)
+				continue
+			}
+			if potentialMerged != nil {
+				rs.stats.Candidates++
+			}
+			report.add(branch, classification, potentialMerged)
+			continue
+		}
+		if progOpts.MaxCommits > 0 {
+			count, err := countUnmergedCommits(rs.baseRev, branch)
+			if err != nil {
+				failed(branch, err)
+				continue
+			}
+			if count > progOpts.MaxCommits {
+				report.add(branch, classTooComplex, nil).Reason = fmt.Sprintf(T("%d unmerged commits"), count)
+				continue
+			}
+		}
+		potentialMerged, err := analyzeBranch(rs.baseRev, branch)
+		if err != nil {
+			failed(branch, err)
+			continue
+		}
+		if _, ok := goneUpstreams[branch]; ok && (potentialMerged == nil || classify(potentialMerged, progOpts) == classUnmerged) {
+			addUpstreamGone(branch)
+			continue
+		}
+		if potentialMerged == nil {
+			report.add(branch, classUnmerged, nil)
+			continue // likely not merged
+		}
+		rs.stats.Candidates++
+		report.add(branch, classify(potentialMerged, progOpts), potentialMerged)
+	}
+
+	if budgeted {
+		if err := saveProgress(progress); err != nil {
+			die(T("failed to save progress: %v\n"), err)
+		}
+	}
+	if err := saveBaseTip(rs.baseName, rs.baseRev); err != nil {
+		warnf(T("failed to record the tip of %s: %v\n"), rs.baseName, err)
+	}
+
+	for _, b := range report.Branches {
+		b.Owners = owners[b.Branch]
+		if directives[b.Branch] == directiveDelete {
+			b.Directive = directiveDelete
+		}
+	}
+
+	if err := markBackups(report); err != nil {
+		die(T("failed to detect backup branches: %v\n"), err)
+	}
+	if err := markMergedIntoDeleted(report); err != nil {
+		die(T("failed to detect branches merged into deleted branches: %v\n"), err)
+	}
+	if !progOpts.Quick {
+		if err := markDuplicates(report, rs.baseRev); err != nil {
+			die(T("failed to detect duplicate branches: %v\n"), err)
+		}
+	}
+
+	if !progOpts.Quick {
+		if err := addDiskUsage(report, rs.baseRev); err != nil {
+			die(T("failed to estimate disk usage: %v\n"), err)
+		}
+		alternates, err := getAlternates()
+		if err != nil {
+			die(T("failed to read the alternate object directories: %v\n"), err)
+		}
+		report.Alternates = alternates
+	}
+
+	if err := addRisks(report); err != nil {
+		die(T("failed to assess the risk of deleting branches: %v\n"), err)
+	}
+
+	if err := rs.addPullRequests(report); err != nil {
+		die(T("failed to find pull requests: %v\n"), err)
+	}
+
+	// branches are reported (and deleted) in a stable order so reports from consecutive runs can be compared
+	if err := sortBranchReports(report.Branches, progOpts.Sort); err != nil {
+		die(T("failed to sort branches: %v\n"), err)
+	}
+
+	if progOpts.GroupBy != "" {
+		report.Groups, err = groupBranchReports(report.Branches, progOpts.GroupBy)
+		if err != nil {
+			die(T("failed to group branches: %v\n"), err)
+		}
+	}
+
+	return report
+}
+
+// cleanup prints the report, and deletes the branches it classified as merged
+func (rs *runState) cleanup(report *Report) {
+	progOpts := rs.progOpts
+
+	// upstreams must be looked up before deleting, since deleting a branch removes its config
+	var forkUpstreams map[string]string
+	if rs.remotes != nil {
+		var err error
+		forkUpstreams, err = getBranchUpstreams()
+		if err != nil {
+			die(T("failed to get branch upstreams: %v\n"), err)
+		}
+	}
+
+	// with --remote, a deleted branch is also deleted from the remote, unless that isn't allowed here
+	var remoteCopies map[string]*RemoteCopy
+	deleteRemote := false
+	remoteDisabled := checkAllowed(opRemoteDelete)
+	if progOpts.Remote != "" {
+		var err error
+		remoteCopies, err = rs.getRemoteCopies(progOpts.Remote)
+		if err != nil {
+			die(T("failed to list the branches of %s: %v\n"), progOpts.Remote, err)
+		}
+		if remoteDisabled != nil && rs.deleteDisabled == nil {
+			warnf(T("not deleting branches from %s: %v\n"), progOpts.Remote, remoteDisabled)
+		}
+		deleteRemote = remoteDisabled == nil && rs.deleteDisabled == nil
+	}
+	// deletions are only announced on the forge, and local deletions held back for them, when they can be carried out
+	var expiry *Expiry
+	if progOpts.ExpiryLabel != "" && remoteCopies != nil && remoteDisabled == nil {
+		var err error
+		if expiry, err = rs.loadExpiry(); err != nil {
+			die(T("failed to load the scheduled deletions: %v\n"), err)
+		}
+	}
+
+	// a failed deletion (e.g. of a branch checked out by a new worktree) doesn't stop the others; failures are summarized at the end
+	remove := func(branchReport *BranchReport) {
+		if err := rs.verifyUnchanged(branchReport); err != nil {
+			warnf(T("not deleting %s: %v\n"), branchReport.Branch, err)
+			branchReport.DeleteError = err.Error()
+			rs.stats.DeleteFailures++
+			return
+		}
+		if branchReport.Branch == rs.currentBranch {
+			if err := rs.switchAway(); err != nil {
+				warnf(T("failed to switch away from %s: %v\n"), branchReport.Branch, err)
+				branchReport.DeleteError = "checked out, and switching away failed: " + err.Error()
+				rs.stats.DeleteFailures++
+				return
+			}
+		}
+		receipt := newReportReceipt(branchReport)
+		if err := rs.removeBranch(receipt); err != nil {
+			warnf(T("failed to delete branch %s: %v\n"), branchReport.Branch, err)
+			branchReport.DeleteError = err.Error()
+			rs.stats.DeleteFailures++
+			return
+		}
+		branchReport.Deleted = true
+		branchReport.Receipt = receipt
+		rs.stats.Deleted++
+		if line, err := json.Marshal(branchReport.Receipt); err == nil {
+			reportf("%s%s\n", receiptPrefix, line)
+		}
+		if c := remoteCopies[branchReport.Branch]; c != nil && deleteRemote {
+			if err := rs.deleteRemoteCopy(progOpts.Remote, receipt.TipSha, c); err != nil {
+				warnf(T("failed to delete %s from %s: %v\n"), c.Name, progOpts.Remote, err)
+				branchReport.DeleteError = fmt.Sprintf("deleted locally, but not from %s: %v", progOpts.Remote, err)
+				rs.stats.DeleteFailures++
+				return
+			}
+			branchReport.RemoteDeleted = true
+			return
+		}
+		// remote deletions are only offered on the fork; the upstream remote isn't ours to clean up
+		if upstream := forkUpstreams[branchReport.Branch]; rs.remotes != nil && rs.isOnFork(upstream) {
+			infof(T("to delete it from %s too: git push --delete %s %s\n"), rs.remotes.Fork, shellQuote(rs.remotes.Fork), shellQuote(remoteBranchName(upstream)))
+		}
+	}
+
+	// in interactive mode, deletions are approved one by one, but only carried out once the whole batch is confirmed
+	// declining only skips a branch for this run, while rejecting it keeps later runs from asking again
+	// each prompt comes with the evidence, and the word diff against the match on request
+	approved := []*BranchReport{}
+	rejections := []*Rejection{}
+	quit := false
+	approve := func(branchReport *BranchReport) {
+		for !quit {
+			if branchReport.Branch == rs.currentBranch {
+				fmt.Fprintf(os.Stderr, T("%s is checked out; deleting it switches to %s first\n"), branchReport.Branch, rs.baseName)
+			}
+			fmt.Fprintf(os.Stderr, "%s: %s\n", branchReport.Branch, describeDeletion(branchReport))
+			switch prompt(T("delete %s? [y/n/d/r/q] (d: show the diff, r: never ask again) "), branchReport.Branch) {
+			case "d":
+				// the word diff goes to stderr, like the prompt, so it doesn't end up in a report written to stdout
+				if err := showWordDiff(branchReport, os.Stderr); err != nil {
+					fmt.Fprintf(os.Stderr, T("failed to show %s: %v\n"), branchReport.Branch, err)
+				}
+			case "y":
+				approved = append(approved, branchReport)
+				return
+			case "n":
+				return
+			case "r":
+				tip, err := getGitRevParse(branchRef(branchReport.Branch))
+				if err != nil {
+					die(T("failed to reject %s: %v\n"), branchReport.Branch, err)
+				}
+				if err := recordDecision(branchReport.Branch, tip, decisionReject); err != nil {
+					die(T("failed to reject %s: %v\n"), branchReport.Branch, err)
+				}
+				rejections = append(rejections, &Rejection{Branch: branchReport.Branch, TipSha: tip, RejectedAt: time.Now()})
+				return
+			case "q":
+				quit = true
+			}
+		}
+	}
+
+	// a sampled run only estimates the impact of a full run, so it never deletes anything
+	// a risky branch (see assessRisks) is only deleted when the risk is accepted (by its author too, with a trailer), or the deletion approved
+	// --max-delete limits the damage of a first run on a neglected repo, should its matches be wrong
+	// otherwise, when deleting is disabled (e.g. --dry-run), the branches which would have been deleted are reported instead
+	// with --expiry-label, a branch on the remote is only deleted once its deletion was announced long enough ago
+	isDue := func(branchReport *BranchReport) bool {
+		due, err := expiry.isDue(branchReport)
+		if err != nil {
+			warnf(T("keeping %s: %v\n"), branchReport.Branch, err)
+		}
+		return due
+	}
+	wouldDelete := 0
+	deleteReported := func(branchReport *BranchReport) {
+		for _, risk := range branchReport.Risks {
+			reportf(T("RISK: %s\n"), risk)
+		}
+		deleted := rs.stats.Deleted + wouldDelete
+		switch {
+		case report.Sample != nil:
+			reportf(T("would delete branch %s\n"), branchReport.Branch)
+		case progOpts.Interactive && rs.deleteDisabled == nil:
+			approve(branchReport)
+		case len(branchReport.Risks) > 0 && !progOpts.AcceptRisk && branchReport.Directive != directiveDelete:
+			reportf(T("keeping %s due to the risks above (see --accept-risk)\n"), branchReport.Branch)
+		case branchReport.Branch == rs.currentBranch && !progOpts.SwitchAway:
+			reportf(T("keeping %s since it is checked out (see --switch-away)\n"), branchReport.Branch)
+		case progOpts.MaxDelete > 0 && deleted >= progOpts.MaxDelete:
+			reportf(T("keeping %s since %d branches were already deleted (see --max-delete)\n"), branchReport.Branch, deleted)
+			rs.stats.Capped++
+		case expiry != nil && remoteCopies[branchReport.Branch] != nil && !isDue(branchReport):
+		case rs.deleteDisabled != nil:
+			reportf(T("would delete branch %s\n"), branchReport.Branch)
+			if c := remoteCopies[branchReport.Branch]; c != nil && remoteDisabled == nil {
+				reportf(T("would delete %s from %s too\n"), c.Name, progOpts.Remote)
+			}
+			wouldDelete++
+		default:
+			remove(branchReport)
+		}
+	}
+
+	// evidence must be exported before any branches are deleted
+	if progOpts.ExportEvidence != "" {
+		if err := exportEvidence(progOpts.ExportEvidence, report.Branches); err != nil {
+			die(T("failed to export evidence: %v\n"), err)
+		}
+	}
+
+	var err error
+	report.Audit, err = auditRepository(progOpts.UseReplaceObjects)
+	if err != nil {
+		die(T("failed to audit repository: %v\n"), err)
+	}
+	for _, warning := range report.Audit {
+		warnf(T("WARNING: analysis may be unreliable: %s\n"), warning)
+	}
+
+	// e.g. to file an issue for each branch which needs review, since printing them is easily missed
+	reviewCommand, err := getGitConfig("branch-cleanup.reviewCommand")
+	if err != nil {
+		die(T("failed to read config: %v\n"), err)
+	}
+
+	groups := map[string]*GroupSummary{}
+	for _, g := range report.Groups {
+		groups[g.Name] = g
+	}
+	// e.g. "pull request #123: Add the widget (merged 2024-01-02)", which means more to a reviewer than the sha
+	reportPullRequest := func(pr *PullRequest) {
+		if pr == nil {
+			return
+		}
+		reportf(T("pull request #%d: %s (merged %s)\n"), pr.Number, pr.Title, pr.MergedAt.Format("2006-01-02"))
+		if pr.URL != "" {
+			reportf("%s\n", pr.URL)
+		}
+	}
+	var currentGroup *GroupSummary
+	for _, branchReport := range report.Branches {
+		branch := branchReport.Branch
+		potentialMerged := branchReport.PotentialMerge
+
+		if g := groups[branchReport.Group]; g != nil && g != currentGroup {
+			currentGroup = g
+			reportf(T("== %s: %d branches, %d candidates, %d bytes of diffs ==\n\n"), g.Name, g.Branches, g.Candidates, g.DiffSize)
+		}
+
+		switch branchReport.Classification {
+		case classInUse:
+			reportf(T("%s is checked out elsewhere (%s)\n"), branch, branchReport.Reason)
+			reportf("\n")
+
+		case classTrackingDuplicate:
+			if !isDeletable(branchReport.Classification, progOpts) {
+				reportf(T("%s points at the tip of %s; keeping it (see --delete-tracking-duplicates)\n"), branch, rs.baseName)
+				reportf("\n")
+				continue
+			}
+			reportf(T("%s points at the tip of %s\n"), branch, rs.baseName)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classMerged:
+			reportf(T("%s was cleanly merged under %s\n"), branch, potentialMerged.MergedSha)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classMatched:
+			reportf(T("%s was merged under %s (subject score: %f; diff score %f)\n"), branch, potentialMerged.MergedSha, potentialMerged.SubjectScore, potentialMerged.DiffScore)
+			if potentialMerged.TreeIdentical {
+				reportf(T("%s has the same tree as %s\n"), branch, potentialMerged.MatchedSha)
+			}
+			reportPullRequest(branchReport.PullRequest)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classRevert:
+			reportf(T("%s is a revert, which was merged under %s (subject score: %f; diff score %f)\n"), branch, potentialMerged.MatchedSha, potentialMerged.SubjectScore, potentialMerged.DiffScore)
+			reportPullRequest(branchReport.PullRequest)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classEmpty:
+			if !isDeletable(branchReport.Classification, progOpts) {
+				reportf(T("%s only contains empty commits; keeping it (see --delete-empty)\n"), branch)
+				reportf("\n")
+				continue
+			}
+			reportf(T("%s only contains empty commits\n"), branch)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classBackup:
+			if !isDeletable(branchReport.Classification, progOpts) {
+				reportf(T("%s is a backup copy, %s (see --clean-backups)\n"), branch, branchReport.Reason)
+				reportf("\n")
+				continue
+			}
+			reportf(T("%s is a backup copy, %s\n"), branch, branchReport.Reason)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classDuplicate:
+			if !isDeletable(branchReport.Classification, progOpts) {
+				reportf(T("%s is a duplicate: %s (see --delete-duplicates)\n"), branch, branchReport.Reason)
+				reportf("\n")
+				continue
+			}
+			reportf(T("%s is a duplicate: %s\n"), branch, branchReport.Reason)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classMergedIntoDeleted:
+			reportf(T("%s was %s\n"), branch, branchReport.Reason)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classUpstreamed:
+			reportf(T("%s was upstreamed: %s\n"), branch, branchReport.Reason)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classUpstreamGone:
+			if !isDeletable(branchReport.Classification, progOpts) {
+				reportf(T("%s may have been merged: its %s (see --delete-upstream-gone)\n"), branch, branchReport.Reason)
+				reportf("\n")
+				continue
+			}
+			reportf(T("%s may have been merged: its %s\n"), branch, branchReport.Reason)
+			deleteReported(branchReport)
+			reportf("\n")
+
+		case classError:
+			reportf(T("%s could not be analyzed: %s\n"), branch, branchReport.Reason)
+			reportf("\n")
+
+		case classTooComplex:
+			reportf(T("%s is too complex to analyze (%s); review it manually\n"), branch, branchReport.Reason)
+			reportf("\n")
+
+		case classRebased:
+			reportf(T("%s was rebased onto %s, which has not been merged\n"), branch, strings.TrimPrefix(potentialMerged.RebasedUpstream, remotePrefix))
+			reportf("\n")
+
+		case classPotential:
+			// Code Diff is not perfect, don't auto-delete
+			reportf(T("%s was **potentially** merged under %s (subject score: %f; diff score %f)\n"), branch, potentialMerged.MergedSha, potentialMerged.SubjectScore, potentialMerged.DiffScore)
+			reportPullRequest(branchReport.PullRequest)
+			if potentialMerged.NumCommits > 1 {
+				reportf(T("WARNING: %s contains %d commits, comparing combined diffs instead (and ommitting commit message)\n"), branch, potentialMerged.NumCommits)
+			}
+			if potentialMerged.MatchedSha != "" && isTextReport() {
+				lines, err := getNearMissDiff(branchReport)
+				if err != nil {
+					warnf(T("failed to compare %s with %s: %v\n"), branch, potentialMerged.MatchedSha, err)
+				} else if len(lines) > 0 {
+					reportf(T("how %s differs from %s:\n"), branch, potentialMerged.MatchedSha)
+					for _, line := range lines {
+						reportf("%s\n", line)
+					}
+				}
+			}
+			if len(potentialMerged.CommitDiffCmds) > 0 {
+				reportf(T("compare each commit with its closest counterpart in %s:\n"), rs.baseName)
+				for _, cmd := range potentialMerged.CommitDiffCmds {
+					reportf("%s\n", cmd)
+				}
+			} else {
+				reportf("%s\n", potentialMerged.DiffCmd)
+			}
+			if reviewCommand != "" && report.Sample == nil {
+				if err := runReviewHook(reviewCommand, rs.baseName, branchReport); err != nil {
+					warnf("%v\n", err)
+				}
+			}
+			if progOpts.Interactive && rs.deleteDisabled == nil && report.Sample == nil {
+				approve(branchReport)
+			} else {
+				reportf("git branch -D -- %s\n", shellQuote(branch))
+			}
+			reportf("\n")
+		}
+	}
+
+	if expiry != nil {
+		if err := expiry.save(report); err != nil {
+			die(T("failed to save the scheduled deletions: %v\n"), err)
+		}
+		rejections = append(rejections, expiry.objections...)
+	}
+
+	if len(rejections) > 0 {
+		if err := addRejections(rejections); err != nil {
+			die(T("failed to save rejections: %v\n"), err)
+		}
+		infof(T("%d branches will no longer be suggested for deletion\n"), len(rejections))
+	}
+
+	if len(approved) > 0 {
+		fmt.Fprint(os.Stderr, T("about to delete:\n"))
+		for _, b := range approved {
+			fmt.Fprintf(os.Stderr, "  %s (%s)\n", b.Branch, b.Classification)
+		}
+		if prompt(T("delete these %d branches? [y/N] "), len(approved)) == "y" {
+			// deleting from the remote affects everyone, so it is confirmed on its own
+			if deleteRemote {
+				onRemote := []string{}
+				for _, b := range approved {
+					if c := remoteCopies[b.Branch]; c != nil {
+						onRemote = append(onRemote, c.Name)
+					}
+				}
+				if len(onRemote) > 0 {
+					fmt.Fprintf(os.Stderr, T("%s also has: %s\n"), progOpts.Remote, strings.Join(onRemote, ", "))
+					deleteRemote = prompt(T("delete these %d branches from %s too? [y/N] "), len(onRemote), progOpts.Remote) == "y"
+				}
+			}
+			if err := saveCheckpoint(approved); err != nil {
+				die(T("failed to save checkpoint: %v\n"), err)
+			}
+			rs.uncapped = true
+			for _, b := range approved {
+				remove(b)
+			}
+			infof(T("to restore them all, run the rollback command\n"))
+		}
+	}
+
+	// deleting with git branch -D drops the branch's config, but other ways of deleting (e.g. jj) may not
+	deleted := map[string]bool{}
+	for _, b := range report.Branches {
+		deleted[b.Branch] = b.Deleted
+	}
+	if rs.deleteDisabled != nil {
+		// nothing was deleted, so there is nothing to clean up after
+	} else if removed, err := removeOrphanedBranchConfig(deleted); err != nil {
+		warnf(T("failed to remove the config of deleted branches: %v\n"), err)
+	} else {
+		for _, name := range removed {
+			infof(T("removed leftover config of deleted branch %s\n"), name)
+		}
+	}
+
+	for _, b := range report.Branches {
+		// archived branches keep their objects
+		if b.Deleted && progOpts.Archive == "" && !progOpts.ArchiveRename {
+			report.Reclaimable += b.DiskUsage
+		}
+	}
+	if report.Reclaimable > 0 {
+		infof(T("the deleted branches retained %s of objects, which git gc prunes once no reflog (e.g. HEAD's) reaches them any more and they are older than gc.pruneExpire\n"), formatBytes(report.Reclaimable))
+	}
+	if len(report.Alternates) > 0 && rs.stats.Deleted > 0 {
+		infof(T("objects shared with %s were not counted, since they are stored there and deleting branches here doesn't free them\n"), strings.Join(report.Alternates, ", "))
+	}
+
+	// git branch -D drops the branch's own reflog, but HEAD's reflog still
+	// pins any commits which were checked out on it
+	if progOpts.ExpireReflogs != "" && rs.stats.Deleted > 0 {
+		tips := []string{}
+		for _, b := range report.Branches {
+			if b.Deleted && b.Receipt != nil {
+				tips = append(tips, b.Receipt.TipSha)
+			}
+		}
+		expired, err := expireDeletedFromReflogs(tips, progOpts.ExpireReflogs)
+		if err != nil {
+			die(T("failed to expire reflogs: %v\n"), err)
+		}
+		infof(T("expired %d HEAD reflog entries of the deleted branches older than %s\n"), expired, progOpts.ExpireReflogs)
+	}
+
+	// deleting refs alone doesn't free any space
+	if progOpts.GC && rs.stats.Deleted > 0 {
+		infof(T("running git gc --prune=%s\n"), progOpts.GCPrune)
+		report.GC, err = runGC(progOpts.GCPrune)
+		if err != nil {
+			die(T("git gc failed: %v\n"), err)
+		}
+		infof(T("repository objects went from %s to %s\n"), formatBytes(report.GC.Before), formatBytes(report.GC.After))
+		if report.GC.After >= report.GC.Before {
+			infof(T("nothing was freed yet: the deleted branches' objects are still referenced by reflogs, or are newer than --gc-prune\n"))
+		}
+	}
+
+	if rs.stats.Capped > 0 {
+		warnf(T("kept %d more branches which could be deleted, since --max-delete=%d was reached; review the deletions, then run again or raise --max-delete\n"), rs.stats.Capped, progOpts.MaxDelete)
+	}
+
+	if rs.stats.DeleteFailures > 0 {
+		warnf(T("failed to delete %d branches:\n"), rs.stats.DeleteFailures)
+		for _, b := range report.Branches {
+			if b.DeleteError != "" {
+				warnf("  %s: %s\n", b.Branch, b.DeleteError)
+			}
+		}
+	}
+
+	if report.Sample != nil {
+		report.Sample.summarize(report.Branches, progOpts)
+		reportf(T("sampled %d of %d branches: %d candidates, %d deletable (estimated %d candidates, %d deletable across all branches)\n"),
+			report.Sample.Analyzed, report.Sample.Eligible, report.Sample.Candidates, report.Sample.Deletable,
+			report.Sample.EstimatedCandidates, report.Sample.EstimatedDeletable)
+	}
+
+	if progOpts.Stats {
+		rs.stats.DurationSeconds = time.Since(rs.stats.Time).Seconds()
+		rs.stats.CacheHits = commitDiffCacheHits
+		rs.stats.CacheMisses = commitDiffCacheMisses
+		if err := appendStats(rs.stats); err != nil {
+			die(T("failed to write stats: %v\n"), err)
+		}
+	}
+}
+
+// switchAway checks out the base instead of the current branch, so that the
+// current branch can be deleted; HEAD is detached when the base isn't a branch
+func (rs *runState) switchAway() error {
+	if err := checkAllowed(opLocalDelete); err != nil {
+		return err
+	}
+	_, err := getGitRevParse(branchRef(rs.baseName))
+	isBranch := err == nil
+	args := []string{"git", "switch", "--quiet", "--detach", rs.baseRev}
+	if isBranch {
+		args = []string{"git", "switch", "--quiet", rs.baseName}
+	}
+	infof(T("switching from %s to %s\n"), rs.currentBranch, rs.baseName)
+	if _, stderr, err := runCommandWithStderr(args...); err != nil {
+		if stderr != "" {
+			return fmt.Errorf("%s", strings.SplitN(stderr, "\n", 2)[0])
+		}
+		return err
+	}
+	rs.currentBranch = ""
+	if isBranch {
+		rs.currentBranch = rs.baseName
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// SampleSummary extrapolates the results of a --sample run to all eligible branches
+type SampleSummary struct {
+	Eligible            int `json:"eligible"`
+	Analyzed            int `json:"analyzed"`
+	Candidates          int `json:"candidates"`
+	Deletable           int `json:"deletable"`
+	EstimatedCandidates int `json:"estimatedCandidates"`
+	EstimatedDeletable  int `json:"estimatedDeletable"`
+}
+
+// sampleBranches picks n branches, either at random or those with the oldest tips
+func sampleBranches(branches []string, n int, order string) ([]string, error) {
+	if n >= len(branches) {
+		return branches, nil
+	}
+	sampled := append([]string{}, branches...)
+	switch order {
+	case "oldest":
+		tipTimes, err := getBranchTipTimes()
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(sampled, func(i, j int) bool {
+			return tipTimes[sampled[i]] < tipTimes[sampled[j]]
+		})
+	default:
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		r.Shuffle(len(sampled), func(i, j int) {
+			sampled[i], sampled[j] = sampled[j], sampled[i]
+		})
+	}
+	return sampled[:n], nil
+}
+
+func (s *SampleSummary) summarize(branches []*BranchReport, progOpts *opts) {
+	for _, b := range branches {
+		if isCandidate(b.Classification) {
+			s.Candidates++
+		}
+		if isDeletable(b.Classification, progOpts) {
+			s.Deletable++
+		}
+	}
+	if s.Analyzed > 0 {
+		s.EstimatedCandidates = s.Candidates * s.Eligible / s.Analyzed
+		s.EstimatedDeletable = s.Deletable * s.Eligible / s.Analyzed
+	}
+}
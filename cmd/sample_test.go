@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+// TestSummarize checks that a sample counts a branch as deletable exactly
+// when cleanup would delete it
+func TestSummarize(t *testing.T) {
+	classes := []string{
+		classMerged, classMatched, classRevert, classMergedIntoDeleted, classUpstreamed,
+		classTrackingDuplicate, classEmpty, classBackup, classDuplicate, classUpstreamGone,
+		classPotential, classUnmerged, classUnknown, classSkipped, classInUse, classError, classTooComplex, classRebased,
+	}
+	branches := []*BranchReport{}
+	for _, class := range classes {
+		branches = append(branches, &BranchReport{Branch: class, Classification: class})
+	}
+	tests := []struct {
+		name      string
+		progOpts  opts
+		deletable int
+	}{
+		{"defaults", opts{}, 5},
+		{"--delete-tracking-duplicates", opts{DeleteTrackingDuplicates: true}, 6},
+		{"--delete-empty", opts{DeleteEmpty: true}, 6},
+		{"--clean-backups", opts{CleanBackups: true}, 6},
+		{"--delete-duplicates", opts{DeleteDuplicates: true}, 6},
+		{"--delete-upstream-gone", opts{DeleteUpstreamGone: true}, 6},
+		{"all", opts{DeleteTrackingDuplicates: true, DeleteEmpty: true, CleanBackups: true, DeleteDuplicates: true, DeleteUpstreamGone: true}, 10},
+	}
+	for _, test := range tests {
+		s := &SampleSummary{Eligible: 4 * len(branches), Analyzed: len(branches)}
+		s.summarize(branches, &test.progOpts)
+		if s.Candidates != 11 {
+			t.Errorf("%s: %d candidates, expected 11", test.name, s.Candidates)
+		}
+		if s.Deletable != test.deletable || s.EstimatedDeletable != 4*test.deletable {
+			t.Errorf("%s: %d deletable (estimated %d), expected %d (estimated %d)", test.name, s.Deletable, s.EstimatedDeletable, test.deletable, 4*test.deletable)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// getRepoPrefix returns the current directory relative to the top of the
+// worktree, e.g. services/foo/ (or "" at the top)
+func getRepoPrefix() (string, error) {
+	return runCommandTrimmedOutput("git", "rev-parse", "--show-prefix")
+}
+
+// resolveScope turns dir (relative to the current directory) into a directory
+// relative to the top of the worktree, or "" when it is the top
+func resolveScope(dir string) (string, error) {
+	prefix, err := getRepoPrefix()
+	if err != nil {
+		return "", err
+	}
+	scope := path.Clean(path.Join(prefix, filepath.ToSlash(dir)))
+	if scope == ".." || strings.HasPrefix(scope, "../") || path.IsAbs(scope) {
+		return "", fmt.Errorf("%s is outside the repository", dir)
+	}
+	if scope == "." {
+		return "", nil
+	}
+	return scope, nil
+}
+
+// scopePathspecs returns the pathspecs matching the files under scope, which
+// (unlike plain paths) mean the same wherever git is run from
+func scopePathspecs(scope string) []string {
+	if scope == "" {
+		return nil
+	}
+	return []string{":(top)" + scope}
+}
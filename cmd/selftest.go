@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// selftestScenario is a branch the selftest repo is built with, and the
+// classifications its analysis may end up with
+type selftestScenario struct {
+	branch      string
+	description string
+	expect      []string
+}
+
+var selftestScenarios = []selftestScenario{
+	{"clean", "merged with a merge commit", []string{classMerged}},
+	{"squash", "two commits squash merged", []string{classMatched}},
+	{"rebase", "rebase merged after the base moved on", []string{classMatched}},
+	{"partial", "only one of two commits cherry-picked", []string{classUnmerged, classPotential}},
+	{"revert", "a revert squash merged", []string{classRevert}},
+	{"unmerged", "never merged", []string{classUnmerged}},
+}
+
+// selftestGit runs a git command in the selftest repo, failing with git's
+// own explanation
+func selftestGit(args ...string) error {
+	_, stderr, err := runCommandWithStderr(append([]string{"git"}, args...)...)
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, stderr)
+	}
+	return nil
+}
+
+// selftestCommit replaces line n (counting from 1) of the file with text, or
+// creates the file when it doesn't exist, and commits it
+func selftestCommit(subject string, file string, n int, text string) error {
+	contents, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := strings.Split(string(contents), "\n")
+	if err != nil {
+		lines = []string{text, ""}
+	} else {
+		lines[n-1] = text
+	}
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return err
+	}
+	if err := selftestGit("add", "--", file); err != nil {
+		return err
+	}
+	return selftestGit("commit", "--quiet", "-m", subject)
+}
+
+// buildSelftestRepo initializes a repo in the current directory with a branch
+// for each of selftestScenarios
+func buildSelftestRepo() error {
+	steps := [][]string{
+		{"init", "--quiet"},
+		{"symbolic-ref", "HEAD", "refs/heads/main"},
+		{"config", "user.name", "selftest"},
+		{"config", "user.email", "selftest@example.com"},
+		{"config", "commit.gpgSign", "false"},
+	}
+	for _, step := range steps {
+		if err := selftestGit(step...); err != nil {
+			return err
+		}
+	}
+	for i := 1; i <= 5; i++ {
+		lines := []string{}
+		for n := 1; n <= 40; n++ {
+			lines = append(lines, fmt.Sprintf("file %d line %d", i, n))
+		}
+		if err := os.WriteFile(fmt.Sprintf("f%d.txt", i), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+
+	c := func(subject, file string, n int, text string) func() error {
+		return func() error { return selftestCommit(subject, file, n, text) }
+	}
+	g := func(args ...string) func() error {
+		return func() error { return selftestGit(args...) }
+	}
+	build := []func() error{
+		g("add", "."), g("commit", "--quiet", "-m", "initial commit"),
+
+		g("checkout", "--quiet", "-b", "clean"), c("Change the first file", "f1.txt", 1, "clean"),
+		g("checkout", "--quiet", "main"), g("merge", "--quiet", "--no-ff", "-m", "Merge branch 'clean'", "clean"),
+
+		g("checkout", "--quiet", "-b", "squash"), c("Squash one", "f2.txt", 2, "squash one"), c("Squash two", "f2.txt", 12, "squash two"),
+		g("checkout", "--quiet", "main"), g("merge", "--quiet", "--squash", "squash"), g("commit", "--quiet", "-m", "Squash (#1)"),
+
+		g("checkout", "--quiet", "-b", "rebase"), c("Change the third file", "f3.txt", 3, "rebase"),
+		g("checkout", "--quiet", "main"), c("Move the base on", "f5.txt", 5, "moved on"), g("cherry-pick", "rebase"),
+
+		g("checkout", "--quiet", "-b", "partial"), c("Partial one", "f4.txt", 4, "partial one"), c("Partial two", "f4.txt", 24, "partial two"),
+		g("checkout", "--quiet", "main"), g("cherry-pick", "partial~1"),
+
+		g("checkout", "--quiet", "-b", "revert"), g("revert", "--no-edit", ":/Move the base on"),
+		g("checkout", "--quiet", "main"), g("merge", "--quiet", "--squash", "revert"), g("commit", "--quiet", "-m", `Revert "Move the base on" (#2)`),
+
+		g("checkout", "--quiet", "-b", "unmerged"), c("Add unmerged work", "new.txt", 1, "unmerged"),
+		g("checkout", "--quiet", "main"), c("Later work on the base", "f5.txt", 15, "later"),
+	}
+	for _, step := range build {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selftestOptions are the given options, less those which pick the branches
+// of the user's repo to analyze, or how they end up, rather than how they are
+// scored, e.g. --protect, --touches, or --quick
+func selftestOptions(progOpts *opts) *opts {
+	testOpts := *progOpts
+	testOpts.Base = "main"
+	testOpts.Quick = false
+	testOpts.Sample = 0
+	testOpts.Limit = 0
+	testOpts.TimeBudget = 0
+	testOpts.MaxCommits = 0
+	testOpts.Touches = nil
+	testOpts.OwnedBy = nil
+	testOpts.Scope = ""
+	testOpts.Branch = ""
+	testOpts.Protect = nil
+	testOpts.IgnorePaths = nil
+	testOpts.Remote = ""
+	testOpts.Fetch = ""
+	testOpts.RecurseSubmodules = false
+	testOpts.ExportEvidence = ""
+	testOpts.Stats = false
+	testOpts.Strict = false
+	return &testOpts
+}
+
+// isolateSelftest resets the process-wide state main set up from the user's
+// environment, repo, and config (e.g. GIT_NAMESPACE, or --protect), which would
+// otherwise apply to the selftest repo too, returning a func restoring it
+func isolateSelftest() func() {
+	savedNamespace, savedBranchPrefix, savedDeletionLogRef := refNamespace, branchPrefix, deletionLogRef
+	savedIgnorePaths, savedIgnoreAttributeCache, savedSparsePathspecs := ignorePaths, ignoreAttributeCache, sparsePathspecs
+	savedProtectPatterns, savedSharedCache, savedAllowedOperation := protectPatterns, sharedCache, allowedOperation
+	savedReportJSON, savedReportTable, savedReportTemplate := reportJSON, reportTable, reportTemplate
+
+	refNamespace = ""
+	branchPrefix = "refs/heads/"
+	deletionLogRef = "refs/branch-cleanup/log"
+	ignorePaths = nil
+	ignoreAttributeCache = map[string]bool{}
+	sparsePathspecs = map[string][]string{}
+	protectPatterns = nil
+	sharedCache = false
+	allowedOperation = nil
+	// the outcome of the scenarios is only reported as text
+	reportJSON, reportTable, reportTemplate = false, false, nil
+
+	return func() {
+		refNamespace, branchPrefix, deletionLogRef = savedNamespace, savedBranchPrefix, savedDeletionLogRef
+		ignorePaths, ignoreAttributeCache, sparsePathspecs = savedIgnorePaths, savedIgnoreAttributeCache, savedSparsePathspecs
+		protectPatterns, sharedCache, allowedOperation = savedProtectPatterns, savedSharedCache, savedAllowedOperation
+		reportJSON, reportTable, reportTemplate = savedReportJSON, savedReportTable, savedReportTemplate
+	}
+}
+
+// selftest builds a temporary repo covering the main ways branches are merged,
+// and checks the analysis classifies each as expected with this git version,
+// platform, and the given options. Nothing outside the temporary repo is changed.
+func selftest(progOpts *opts) error {
+	dir, err := os.MkdirTemp("", "git-branch-cleanup-selftest-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	defer os.Chdir(cwd)
+
+	// the repo must be built identically everywhere, whatever the environment or user config
+	for _, name := range []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_OBJECT_DIRECTORY", "GIT_ALTERNATE_OBJECT_DIRECTORIES", "GIT_NAMESPACE", "XDG_CONFIG_HOME"} {
+		os.Unsetenv(name)
+	}
+	os.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	os.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	os.Setenv("HOME", dir)
+	defer isolateSelftest()()
+	if err := buildSelftestRepo(); err != nil {
+		return fmt.Errorf("failed to build the test repo: %w", err)
+	}
+
+	// only the outcome of each scenario is printed, not how the scratch repo was analyzed
+	logQuiet = true
+	report := newRunState(selftestOptions(progOpts)).analyze()
+	logQuiet = false
+	classifications := map[string]string{}
+	for _, b := range report.Branches {
+		classifications[b.Branch] = b.Classification
+	}
+
+	failed := 0
+	for _, s := range selftestScenarios {
+		got := classifications[s.branch]
+		ok := false
+		for _, c := range s.expect {
+			ok = ok || got == c
+		}
+		if ok {
+			reportf(T("ok   %s (%s): %s\n"), s.branch, s.description, got)
+		} else {
+			failed++
+			reportf(T("FAIL %s (%s): %s, expected %s\n"), s.branch, s.description, got, strings.Join(s.expect, T(" or ")))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scenarios were misclassified; don't rely on deletions without reviewing them", failed, len(selftestScenarios))
+	}
+	return nil
+}
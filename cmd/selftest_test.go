@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSelftestIsolated checks that the selftest's scenarios aren't affected by
+// the state the user's environment, repo, and options set up
+func TestSelftestIsolated(t *testing.T) {
+	// selftest points these at its scratch repo for good
+	for _, name := range []string{"HOME", "GIT_CONFIG_NOSYSTEM", "GIT_CONFIG_GLOBAL", "GIT_NAMESPACE", "XDG_CONFIG_HOME", "GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_OBJECT_DIRECTORY", "GIT_ALTERNATE_OBJECT_DIRECTORIES"} {
+		t.Setenv(name, os.Getenv(name))
+	}
+	t.Setenv("GIT_NAMESPACE", "elsewhere")
+	restore := isolateSelftest()
+	defer restore()
+	refNamespace = getRefNamespace("elsewhere")
+	branchPrefix = refNamespace + "refs/heads/"
+	deletionLogRef = refNamespace + "refs/branch-cleanup/log"
+	protectPatterns = []string{"*"}
+	ignorePaths = []string{"*.txt"}
+
+	var progOpts opts
+	if _, err := newTestParser(t, &progOpts).ParseArgs([]string{"--quick", "--touches=elsewhere/", "--protect=*", "--limit=1", "--ignore-paths=*.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := selftest(&progOpts); err != nil {
+		t.Fatal(err)
+	}
+	if refNamespace != getRefNamespace("elsewhere") || len(protectPatterns) != 1 {
+		t.Errorf("selftest didn't restore the state it reset")
+	}
+}
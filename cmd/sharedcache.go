@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// sharedCache stores the commit cache (see warmCache) in the user's cache
+// directory rather than the repo's, shared by every clone of the same remote
+// repository; commits are immutable, so a sha's diff is the same in each
+var sharedCache bool
+
+// getSharedCacheKey identifies the repository a clone was cloned from, by the
+// URL of origin (or else the first remote), normalized so that the ssh and
+// https URLs of a repository share a key
+func getSharedCacheKey() (string, error) {
+	remotes, err := getRemotes()
+	if err != nil {
+		return "", err
+	}
+	if len(remotes) == 0 {
+		return "", fmt.Errorf("the cache is shared by remote URL, but there are no remotes")
+	}
+	remote := remotes[0]
+	for _, r := range remotes {
+		if r == "origin" {
+			remote = r
+		}
+	}
+	url, err := getRemoteWebURL(remote)
+	if err != nil {
+		return "", err
+	}
+	if url == "" {
+		// a local path, which is only shared by clones of the same directory
+		if url, err = getGitConfig("remote." + remote + ".url"); err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// getSharedCacheDir returns the directory of the shared cache of this
+// repository, following the XDG base directory spec
+func getSharedCacheDir() (string, error) {
+	key, err := getSharedCacheKey()
+	if err != nil {
+		return "", err
+	}
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "git-branch-cleanup", key), nil
+}
+
+// getCommitCachePath returns where the commit cache is stored
+func getCommitCachePath() (string, error) {
+	if !sharedCache {
+		return getStateFilePath(commitCacheFileName)
+	}
+	dir, err := getSharedCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, commitCacheFileName), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// ShortStat is the size of a change, as summarized by git diff --shortstat
+type ShortStat struct {
+	Files      int
+	Insertions int
+	Deletions  int
+}
+
+var (
+	shortStatFiles      = regexp.MustCompile(`(\d+) files? changed`)
+	shortStatInsertions = regexp.MustCompile(`(\d+) insertions?\(\+\)`)
+	shortStatDeletions  = regexp.MustCompile(`(\d+) deletions?\(-\)`)
+)
+
+// minStatScore is the shortstat similarity below which a match isn't worth a full diff comparison (0 disables)
+var minStatScore float32
+
+var commitShortStatCache = map[string]*ShortStat{}
+
+func parseShortStat(out string) *ShortStat {
+	stat := &ShortStat{}
+	for re, n := range map[*regexp.Regexp]*int{shortStatFiles: &stat.Files, shortStatInsertions: &stat.Insertions, shortStatDeletions: &stat.Deletions} {
+		if m := re.FindStringSubmatch(out); m != nil {
+			*n, _ = strconv.Atoi(m[1])
+		}
+	}
+	return stat
+}
+
+func getShortStat(start, end string) (*ShortStat, error) {
+	out, err := runCommandTrimmedOutput(append([]string{"git", "--no-pager", "diff", "--shortstat", start + ".." + end, "--"}, ignoredPathspecs()...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseShortStat(out), nil
+}
+
+func getCommitShortStat(commit string) (*ShortStat, error) {
+	if stat, ok := commitShortStatCache[commit]; ok {
+		return stat, nil
+	}
+	out, err := runCommandTrimmedOutput(append([]string{"git", "--no-pager", "show", "--shortstat", "--format=", commit, "--"}, ignoredPathspecs()...)...)
+	if err != nil {
+		return nil, err
+	}
+	stat := parseShortStat(out)
+	commitShortStatCache[commit] = stat
+	return stat, nil
+}
+
+// similarity compares the sizes of two changes, from 0 (nothing alike) to 1 (the same counts)
+func (s *ShortStat) similarity(o *ShortStat) float32 {
+	ratio := func(a, b int) float32 {
+		if a == b {
+			return 1
+		}
+		if a > b {
+			a, b = b, a
+		}
+		return float32(a) / float32(b)
+	}
+	return (ratio(s.Files, o.Files) + ratio(s.Insertions, o.Insertions) + ratio(s.Deletions, o.Deletions)) / 3
+}
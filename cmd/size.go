@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// getDiskUsage estimates the bytes of objects which only the branch retains,
+// i.e. which aren't reachable from the base, any remote-tracking branch, or
+// the refs of an alternate (whose objects are stored there, see getAlternates)
+func getDiskUsage(baseRev, branch string) (int64, error) {
+	out, err := runCommandTrimmedOutput("git", "rev-list", "--objects", "--disk-usage", branchRef(branch), "--not", baseRev, "--remotes", "--alternate-refs", "--")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(out, 10, 64)
+}
+
+// addDiskUsage records the disk usage of each branch which could be deleted
+func addDiskUsage(report *Report, baseRev string) error {
+	for _, b := range report.Branches {
+		if !isCandidate(b.Classification) {
+			continue
+		}
+		usage, err := getDiskUsage(baseRev, b.Branch)
+		if err != nil {
+			return err
+		}
+		b.DiskUsage = usage
+	}
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
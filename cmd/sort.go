@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// getBranchTipTimes returns the committer time (unix seconds) of each branch's tip
+func getBranchTipTimes() (map[string]int64, error) {
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(committerdate:unix) %(refname)", branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	tipTimes := map[string]int64{}
+	for _, line := range lines {
+		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		t, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		tipTimes[strings.TrimPrefix(parts[1], branchPrefix)] = t
+	}
+	return tipTimes, nil
+}
+
+// sortBranchReports orders branches by name, age (oldest tip first), score
+// (highest first), or size (largest diff first); ties are always broken by name
+func sortBranchReports(reports []*BranchReport, order string) error {
+	var less func(a, b *PotentialMerge) bool
+	switch order {
+	case "age":
+		tipTimes, err := getBranchTipTimes()
+		if err != nil {
+			return err
+		}
+		less = func(a, b *PotentialMerge) bool {
+			return tipTimes[a.Branch] < tipTimes[b.Branch]
+		}
+	case "score":
+		less = func(a, b *PotentialMerge) bool {
+			if a.DiffScore != b.DiffScore {
+				return a.DiffScore > b.DiffScore
+			}
+			return a.SubjectScore > b.SubjectScore
+		}
+	case "size":
+		less = func(a, b *PotentialMerge) bool {
+			return a.DiffSize > b.DiffSize
+		}
+	default:
+		less = func(a, b *PotentialMerge) bool {
+			return false
+		}
+	}
+	// branches which weren't analyzed sort as if they had zero scores
+	merge := func(r *BranchReport) *PotentialMerge {
+		if r.PotentialMerge != nil {
+			return r.PotentialMerge
+		}
+		return &PotentialMerge{Branch: r.Branch}
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		a, b := merge(reports[i]), merge(reports[j])
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return reports[i].Branch < reports[j].Branch
+	})
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ghstack pushes each commit of a stack as gh/<user>/<n>/{base,head,orig,next}
+var ghstackBranchRegexp = regexp.MustCompile(`^gh/[^/]+/[0-9]+/(base|head|orig|next)$`)
+
+// graphite records per-branch metadata under refs/branch-metadata/<branch>
+const graphiteMetadataPrefix = "refs/branch-metadata/"
+
+func getGitCommonDir() (string, error) {
+	return runCommandTrimmedOutput("git", "rev-parse", "--git-common-dir")
+}
+
+func getGitPath(path string) (string, error) {
+	return runCommandTrimmedOutput("git", "rev-parse", "--git-path", path)
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// branchlessObservesRefs returns true when git-branchless's reference-transaction
+// hook is installed, in which case branchless is informed of any branch we delete.
+func branchlessObservesRefs() bool {
+	hook, err := getGitPath("hooks/reference-transaction")
+	if err != nil {
+		return false
+	}
+	contents, err := os.ReadFile(hook)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(contents), "branchless")
+}
+
+// getStackToolBranches returns a map of branch name to the stacked-diff tool
+// (ghstack, graphite, or git-branchless) which is tracking that branch.
+func getStackToolBranches(branches []string) (map[string]string, error) {
+	tracked := map[string]string{}
+
+	for _, branch := range branches {
+		if ghstackBranchRegexp.MatchString(branch) {
+			tracked[branch] = "ghstack"
+		}
+	}
+
+	lines, err := runCommandSplitLines("git", "for-each-ref", "--format=%(refname)", graphiteMetadataPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		branch := strings.TrimPrefix(strings.TrimSpace(line), graphiteMetadataPrefix)
+		if branch != "" {
+			tracked[branch] = "graphite"
+		}
+	}
+
+	gitDir, err := getGitCommonDir()
+	if err != nil {
+		return nil, err
+	}
+	if pathExists(gitDir+"/branchless") && !branchlessObservesRefs() {
+		// without its hooks, branchless won't notice deletions; leave everything alone
+		for _, branch := range branches {
+			if _, ok := tracked[branch]; !ok {
+				tracked[branch] = "git-branchless"
+			}
+		}
+	}
+
+	return tracked, nil
+}
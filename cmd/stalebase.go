@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// getBaseLag returns how many commits the base branch is behind its upstream,
+// and the upstream; "" when the base isn't a branch with an upstream
+func getBaseLag(baseName, baseRev string) (int, string, error) {
+	if _, err := getGitRevParse(branchRef(baseName)); err != nil {
+		return 0, "", nil // e.g. a tag, commit, or remote-tracking branch
+	}
+	upstream, err := getUpstream(baseName)
+	if err != nil || upstream == "" {
+		return 0, "", err
+	}
+	if _, err := getGitRevParse(upstream); err != nil {
+		return 0, "", nil // the upstream is gone, or was never fetched
+	}
+	out, err := runCommandTrimmedOutput("git", "rev-list", "--count", baseRev+".."+upstream, "--")
+	if err != nil {
+		return 0, "", err
+	}
+	behind, err := strconv.Atoi(out)
+	return behind, strings.TrimPrefix(upstream, remotePrefix), err
+}
+
+// checkBaseLag warns, or with --stale-base=abort fails, when the base is more
+// than --max-base-lag commits behind its upstream. Analyzing against a stale
+// base misses the branches merged since, and can match against old history
+// which was since reverted or rewritten.
+func (rs *runState) checkBaseLag() error {
+	if rs.progOpts.StaleBase == "ignore" {
+		return nil
+	}
+	behind, upstream, err := getBaseLag(rs.baseName, rs.baseRev)
+	if err != nil {
+		return err
+	}
+	if upstream == "" || behind <= rs.progOpts.MaxBaseLag {
+		return nil
+	}
+	if rs.progOpts.StaleBase == "abort" {
+		return fmt.Errorf("%s is %d commits behind %s; update it first (e.g. git pull), or pass --stale-base=warn", rs.baseName, behind, upstream)
+	}
+	warnf(T("WARNING: %s is %d commits behind %s, so branches merged since will be missed; update it first (e.g. git pull)\n"), rs.baseName, behind, upstream)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const statsFileName = "stats.jsonl"
+
+// RunStats is appended (one JSON object per line) to the stats file after each run
+type RunStats struct {
+	Time            time.Time `json:"time"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	Branches        int       `json:"branches"`
+	Analyzed        int       `json:"analyzed"`
+	Candidates      int       `json:"candidates"`
+	Deleted         int       `json:"deleted"`
+	CacheHits       int       `json:"cacheHits"`
+	CacheMisses     int       `json:"cacheMisses"`
+	Errors          int       `json:"errors"` // branches which could not be analyzed
+	DeleteFailures  int       `json:"deleteFailures"`
+	Capped          int       `json:"capped"` // branches kept since --max-delete was reached
+}
+
+// getStateDir returns the directory where git-branch-cleanup keeps per-repo state
+func getStateDir() (string, error) {
+	gitDir, err := getGitCommonDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "branch-cleanup"), nil
+}
+
+func getStatsPath() (string, error) {
+	stateDir, err := getStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, statsFileName), nil
+}
+
+func appendStats(stats *RunStats) error {
+	path, err := getStatsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\n", line)
+	return err
+}
+
+func readStats() ([]*RunStats, error) {
+	path, err := getStatsPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	history := []*RunStats{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var stats RunStats
+		if err := json.Unmarshal([]byte(line), &stats); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		history = append(history, &stats)
+	}
+	return history, scanner.Err()
+}
+
+const statsGraphWidth = 50
+
+// showStats graphs the number of branches over the recorded run history
+func showStats() error {
+	history, err := readStats()
+	if err != nil {
+		return err
+	}
+	maxBranches := 1
+	for _, stats := range history {
+		if stats.Branches > maxBranches {
+			maxBranches = stats.Branches
+		}
+	}
+	for _, stats := range history {
+		bar := strings.Repeat("#", stats.Branches*statsGraphWidth/maxBranches)
+		fmt.Printf(T("%s %5d branches %-*s (deleted %d; %.1fs; cache hits %d/%d)\n"),
+			stats.Time.Local().Format("2006-01-02 15:04"), stats.Branches, statsGraphWidth, bar,
+			stats.Deleted, stats.DurationSeconds, stats.CacheHits, stats.CacheHits+stats.CacheMisses)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // absent for notifications, which get no response
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcBranchParams are the params of the analyze and delete methods
+type rpcBranchParams struct {
+	Branch string `json:"branch"`
+}
+
+// stdioServer keeps the analysis of the branches between requests, until
+// their refs change or it is sent SIGHUP
+type stdioServer struct {
+	progOpts *opts
+	hup      chan os.Signal
+	rs       *runState
+	stamp    string                   // see getRefsStamp
+	report   *Report                  // the analysis of every branch, once candidates is requested
+	analyzed map[string]*BranchReport // the branches analyzed so far
+}
+
+// serveStdio answers JSON-RPC 2.0 requests, one JSON value each, from stdin on
+// stdout until stdin is closed. The methods are:
+//
+//	analyze {"branch": name}  the branch's report
+//	candidates                the reports of the branches which could be deleted
+//	delete {"branch": name}   deletes the branch, if it still could be
+//
+// Branches are only analyzed again once refs change (or on SIGHUP, e.g. after
+// changing config), and the commit diffs computed are reused regardless, so
+// frontends only pay for a full scan once.
+func serveStdio(progOpts *opts, in io.Reader, out io.Writer) error {
+	// nothing but responses may be written to stdout
+	reportJSON = true
+
+	s := &stdioServer{progOpts: progOpts, hup: make(chan os.Signal, 1)}
+	signal.Notify(s.hup, syscall.SIGHUP)
+	defer signal.Stop(s.hup)
+
+	decoder := json.NewDecoder(in)
+	encoder := json.NewEncoder(out)
+	for {
+		var req rpcRequest
+		err := decoder.Decode(&req)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// the stream can't be resynchronized after malformed JSON
+			encoder.Encode(&rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			return err
+		}
+		result, rpcErr := s.handle(&req)
+		if req.ID == nil {
+			continue
+		}
+		if err := encoder.Encode(&rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}); err != nil {
+			return err
+		}
+	}
+}
+
+// refresh discards what was analyzed when refs changed since, or SIGHUP was
+// received, re-reading the branches, config, and commit cache
+func (s *stdioServer) refresh() {
+	hup := false
+	select {
+	case <-s.hup:
+		hup = true
+	default:
+	}
+	stamp, err := getRefsStamp()
+	if err != nil {
+		warnf(T("failed to check whether refs changed: %v\n"), err)
+		stamp = ""
+	}
+	if s.rs != nil && !hup && stamp != "" && stamp == s.stamp {
+		return
+	}
+	if s.rs != nil {
+		debugf("refreshing: refs changed or SIGHUP was received")
+	}
+	s.rs = newRunState(s.progOpts)
+	s.stamp = stamp
+	s.report = nil
+	s.analyzed = map[string]*BranchReport{}
+}
+
+func (s *stdioServer) handle(req *rpcRequest) (interface{}, *rpcError) {
+	var params rpcBranchParams
+	switch req.Method {
+	case "analyze", "delete":
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Branch == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: `expected {"branch": name}`}
+		}
+	case "candidates":
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+
+	s.refresh()
+	if req.Method == "candidates" {
+		if s.report == nil {
+			s.report = s.rs.analyze()
+			for _, b := range s.report.Branches {
+				s.analyzed[b.Branch] = b
+			}
+		}
+		return getCandidates(s.report), nil
+	}
+
+	b, ok := s.analyzed[params.Branch]
+	if !ok {
+		report, err := s.rs.analyzeOnly(params.Branch)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("%s: %v", params.Branch, err)}
+		}
+		if len(report.Branches) == 0 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("%s is the current branch", params.Branch)}
+		}
+		b = report.Branches[0]
+		s.analyzed[b.Branch] = b
+	}
+	if req.Method == "analyze" {
+		return b, nil
+	}
+
+	// refresh re-analyzes the branch if it changed since the frontend last saw it
+	if !isCandidate(b.Classification) {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("%s is %s, so it is not deleted", b.Branch, b.Classification)}
+	}
+	if err := s.rs.verifyUnchanged(b); err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("not deleting %s: %v", b.Branch, err)}
+	}
+	receipt := newReportReceipt(b)
+	if err := s.rs.removeBranch(receipt); err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: fmt.Sprintf("failed to delete %s: %v", b.Branch, err)}
+	}
+	b.Deleted = true
+	b.Receipt = receipt
+	return b, nil
+}
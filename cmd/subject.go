@@ -0,0 +1,50 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// stripSubjectEmoji removes emoji (and :shortcode: emoji) from subjects before scoring
+var stripSubjectEmoji bool
+
+// e.g. :sparkles: as used by gitmoji
+var emojiShortcodeRegexp = regexp.MustCompile(`:[a-z0-9_+-]+:`)
+
+// isEmojiOrInvisible returns true for emoji, their modifiers and joiners, and zero-width characters
+func isEmojiOrInvisible(r rune) bool {
+	switch {
+	case r == '\u200b', r == '\u200c', r == '\u200d', r == '\u2060', r == '\ufeff': // zero-width space/non-joiner/joiner, word joiner, BOM
+		return true
+	case r >= '\ufe00' && r <= '\ufe0f': // variation selectors
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // skin tone modifiers
+		return true
+	case r >= 0x1f000 && r <= 0x1faff: // emoji and pictographs
+		return true
+	case r >= 0x2600 && r <= 0x27bf: // miscellaneous symbols and dingbats
+		return true
+	}
+	return unicode.Is(unicode.So, r)
+}
+
+// normalizeSubject prepares a commit subject for similarity scoring: it is
+// NFC normalized so precomposed and decomposed characters compare equal, and
+// optionally has emoji and zero-width characters removed.
+func normalizeSubject(subject string) string {
+	subject = norm.NFC.String(subject)
+	if !stripSubjectEmoji {
+		return subject
+	}
+	subject = emojiShortcodeRegexp.ReplaceAllString(subject, "")
+	subject = strings.Map(func(r rune) rune {
+		if isEmojiOrInvisible(r) {
+			return -1
+		}
+		return r
+	}, subject)
+	return strings.Join(strings.Fields(subject), " ")
+}
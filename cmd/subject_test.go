@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject    string
+		stripEmoji bool
+		expected   string
+	}{
+		{"Add the widget", false, "Add the widget"},
+		{"Add the widget (#123)", false, "Add the widget (#123)"}, // scored as is; parsePullRequest reads the number
+		{"Cafe\u0301 menu", false, "Caf\u00e9 menu"},              // decomposed, then precomposed
+		{":sparkles: Add the widget", false, ":sparkles: Add the widget"},
+		{":sparkles: Add the widget", true, "Add the widget"},
+		{"🚀 Ship​ it  ✨", true, "Ship it"},
+		{"Fix 👍🏽 reactions", true, "Fix reactions"},
+	}
+	defer func(strip bool) { stripSubjectEmoji = strip }(stripSubjectEmoji)
+	for _, test := range tests {
+		stripSubjectEmoji = test.stripEmoji
+		if got := normalizeSubject(test.subject); got != test.expected {
+			t.Errorf("normalizeSubject(%q) with stripEmoji=%v = %q, expected %q", test.subject, test.stripEmoji, got, test.expected)
+		}
+	}
+}
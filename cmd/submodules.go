@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// SubmoduleReport is the report of a submodule analyzed with --recurse-submodules
+type SubmoduleReport struct {
+	Path string `json:"path"` // relative to the top of the superproject
+	*Report
+}
+
+// getSubmodulePaths returns the absolute paths of the initialized submodules, recursively
+func getSubmodulePaths() ([]string, error) {
+	lines, err := runCommandSplitLines("git", "submodule", "--quiet", "foreach", "--recursive", `echo "$toplevel/$sm_path"`)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, line := range lines {
+		if line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, nil
+}
+
+// getSubmoduleBase returns the default branch of the submodule in dir: its
+// origin's, since submodules are usually checked out at a detached HEAD and
+// their local branches are rarely kept up to date, or else a conventionally
+// named local branch. It returns "" when there is neither.
+func getSubmoduleBase(dir string) (string, error) {
+	if base, err := runCommandTrimmedOutput("git", "-C", dir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil && base != "" {
+		return base, nil
+	}
+	for _, name := range []string{"main", "master", "trunk"} {
+		if _, err := runCommandTrimmedOutput("git", "-C", dir, "rev-parse", "--verify", "--quiet", branchRef(name)); err == nil {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// cleanupSubmodules runs the same analysis and cleanup in each initialized
+// submodule, against its own default branch, adding their reports to report.
+// It returns false when any branch couldn't be analyzed or deleted.
+func cleanupSubmodules(progOpts *opts, report *Report) bool {
+	top, err := runCommandTrimmedOutput("git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		die(T("failed to find the top of the worktree: %v\n"), err)
+	}
+	paths, err := getSubmodulePaths()
+	if err != nil {
+		die(T("failed to list submodules: %v\n"), err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		die(T("failed to get the current directory: %v\n"), err)
+	}
+	defer os.Chdir(cwd)
+
+	complete := true
+	for _, dir := range paths {
+		path, err := filepath.Rel(top, dir)
+		if err != nil {
+			path = dir
+		}
+		base, err := getSubmoduleBase(dir)
+		if err != nil || base == "" {
+			warnf(T("skipping submodule %s: it has no default branch to compare against\n"), path)
+			continue
+		}
+		if err := os.Chdir(dir); err != nil {
+			die(T("failed to enter submodule %s: %v\n"), path, err)
+		}
+
+		// branch names are only unique within a repository
+		sparsePathspecs = map[string][]string{}
+		subOpts := *progOpts
+		subOpts.Base = base
+		subOpts.Scope = "" // relative to the superproject
+		subOpts.RecurseSubmodules = false
+
+		reportf(T("== submodule %s (against %s) ==\n\n"), filepath.ToSlash(path), base)
+		rs := newRunState(&subOpts)
+		subReport := rs.analyze()
+		rs.cleanup(subReport)
+		report.Submodules = append(report.Submodules, &SubmoduleReport{Path: filepath.ToSlash(path), Report: subReport})
+		if rs.stats.Errors > 0 || rs.stats.DeleteFailures > 0 {
+			complete = false
+		}
+	}
+	return complete
+}
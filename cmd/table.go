@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// reportTable is set by --output=table, in which case the branches are
+// reported as a table (fitted to the terminal) instead of with the human-readable report lines
+var reportTable bool
+
+// noTruncate keeps the table from truncating columns to fit the terminal
+var noTruncate bool
+
+const (
+	minBranchWidth = 16 // the branch column is never truncated narrower than this
+	shortShaLength = 10
+)
+
+// truncate shortens s to at most width characters, marking that it was cut
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	if width == 1 {
+		return "…"
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", width-utf8.RuneCountInString(s))
+}
+
+func shortSha(sha string) string {
+	if len(sha) > shortShaLength {
+		return sha[:shortShaLength]
+	}
+	return sha
+}
+
+// isMatchReported returns true when the commit a branch matched explains its
+// classification; other branches' best matches weren't good enough to matter
+func isMatchReported(b *BranchReport) bool {
+	return b.PotentialMerge != nil && b.MatchedSha != "" && (b.Classification == classMatched || b.Classification == classRevert || b.Classification == classPotential)
+}
+
+// getTableDetail describes a branch in a word: the subject of the commit it matched, or why it was classified as it was
+func getTableDetail(b *BranchReport) string {
+	detail := b.Reason
+	if isMatchReported(b) {
+		if subject, err := getCommitSubject(b.MatchedSha); err == nil {
+			detail = subject
+		}
+	}
+	if b.DeleteError != "" {
+		detail = b.DeleteError
+	}
+	return detail
+}
+
+// dropEmptyColumns removes the columns (after the first two) which are empty
+// in every row but the header, e.g. DELETED when nothing was deleted
+func dropEmptyColumns(rows [][]string) [][]string {
+	keep := []int{}
+	for i := range rows[0] {
+		for _, row := range rows[1:] {
+			if i < 2 || row[i] != "" {
+				keep = append(keep, i)
+				break
+			}
+		}
+	}
+	kept := [][]string{}
+	for _, row := range rows {
+		cells := []string{}
+		for _, i := range keep {
+			cells = append(cells, row[i])
+		}
+		kept = append(kept, cells)
+	}
+	return kept
+}
+
+// writeTable reports the branches as a table; when width is given (i.e. stdout
+// is a terminal), the branch and detail columns are truncated to fit it
+func writeTable(w io.Writer, branches []*BranchReport, width int) error {
+	header := []string{T("BRANCH"), T("STATUS"), T("MATCHED"), T("SUBJECT"), T("DIFF"), T("DELETED"), T("DETAIL")}
+	rows := [][]string{header}
+	for _, b := range branches {
+		matched, subjectScore, diffScore := "", "", ""
+		if isMatchReported(b) {
+			matched = shortSha(b.MatchedSha)
+			subjectScore = strconv.FormatFloat(float64(b.SubjectScore), 'f', 2, 32)
+			diffScore = strconv.FormatFloat(float64(b.DiffScore), 'f', 2, 32)
+		}
+		deleted := ""
+		if b.Deleted {
+			deleted = T("yes")
+		}
+		rows = append(rows, []string{b.Branch, b.Classification, matched, subjectScore, diffScore, deleted, getTableDetail(b)})
+	}
+
+	rows = dropEmptyColumns(rows)
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	branchColumn, detailColumn := 0, len(widths)-1
+	if width > 0 {
+		// the other columns are short, so only the branch and detail columns give way
+		available := width - 2*(len(widths)-1)
+		for i, w := range widths {
+			if i != branchColumn && i != detailColumn {
+				available -= w
+			}
+		}
+		if widths[branchColumn]+widths[detailColumn] > available {
+			branchWidth := widths[branchColumn]
+			if branchWidth > available/2 {
+				branchWidth = available / 2
+				if branchWidth < minBranchWidth {
+					branchWidth = minBranchWidth
+				}
+				if branchWidth > widths[branchColumn] {
+					branchWidth = widths[branchColumn]
+				}
+			}
+			widths[branchColumn] = branchWidth
+			widths[detailColumn] = available - branchWidth
+			if widths[detailColumn] < 0 {
+				widths[detailColumn] = 0
+			}
+		}
+	}
+
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cell = truncate(cell, widths[i])
+			if i < len(row)-1 {
+				cell = pad(cell, widths[i])
+			}
+			cells[i] = cell
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, "  "), " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReportTable writes the table to stdout, fitted to the terminal unless --no-truncate
+func writeReportTable(branches []*BranchReport) error {
+	width := 0
+	if !noTruncate {
+		width = getTerminalWidth(os.Stdout.Fd())
+	}
+	return writeTable(os.Stdout, branches, width)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package main
+
+// getTerminalWidth returns 0, i.e. no terminal, where the width can't be detected
+func getTerminalWidth(fd uintptr) int {
+	return 0
+}
@@ -0,0 +1,74 @@
+package main
+
+// getBranchChangesStart returns the commit which the branch's changes are
+// relative to: where it diverged from the base. Once a branch is merged by
+// ancestry where it diverged can't be told apart from the base's history, so
+// only its tip commit is considered. It returns "" for unrelated histories and
+// root commits.
+func getBranchChangesStart(baseRev, branch string) (string, error) {
+	ref := branchRef(branch)
+	mergeBase, err := getGitMergeBase(baseRev, ref)
+	if err != nil {
+		return "", nil // unrelated history
+	}
+	branchSha, err := getGitRevParse(ref)
+	if err != nil {
+		return "", err
+	}
+	if mergeBase == branchSha {
+		if mergeBase, err = getGitRevParse(ref + "^"); err != nil {
+			return "", nil // a root commit
+		}
+	}
+	return mergeBase, nil
+}
+
+// getBranchChangedFiles returns the files the branch changes, limited to those
+// matching pathspecs (when given)
+func getBranchChangedFiles(baseRev, branch string, pathspecs []string) ([]string, error) {
+	start, err := getBranchChangesStart(baseRev, branch)
+	if err != nil || start == "" {
+		return nil, err
+	}
+	args := []string{"git", "--no-pager", "diff", "--name-only", "--no-renames", start + ".." + branchRef(branch), "--"}
+	lines, err := runCommandSplitLines(append(args, pathspecs...)...)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, line := range lines {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// touchesPaths returns true when the branch changes any file matching the
+// pathspecs (e.g. services/payments/ or '*.proto')
+func touchesPaths(baseRev, branch string, pathspecs []string) (bool, error) {
+	files, err := getBranchChangedFiles(baseRev, branch, pathspecs)
+	return len(files) > 0, err
+}
+
+// maxSparseFiles limits how many files a branch's base history is restricted
+// to, beyond which the pathspecs would cost more than they save
+const maxSparseFiles = 1000
+
+// sparsePathspecs restricts the base commits each branch is compared against
+// to those touching the paths --touches or --owned-by filtered the branch on.
+// Any commit the branch was merged as must touch them too, so in a monorepo
+// this shrinks the comparison to a fraction of the base history.
+var sparsePathspecs = map[string][]string{}
+
+// literalPathspecs turns file names into pathspecs matching exactly those files
+func literalPathspecs(files []string) []string {
+	if len(files) == 0 || len(files) > maxSparseFiles {
+		return nil
+	}
+	pathspecs := []string{}
+	for _, file := range files {
+		pathspecs = append(pathspecs, ":(literal)"+file)
+	}
+	return pathspecs
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+)
+
+// directives an author can leave in a branch's tip commit, as a trailer
+const (
+	directiveKeep   = "keep"   // never delete the branch
+	directiveDelete = "delete" // delete the branch once merged, even when it is riskier than usual
+)
+
+// getBranchDirectives returns the directive of each branch whose tip commit
+// has a trailer opting it in or out of cleanup, e.g.
+//
+//	Branch-cleanup: keep
+//	Cleanup-after-merge: yes
+//
+// so authors can decide at commit time, and the decision travels with the
+// branch. The last such trailer wins.
+func getBranchDirectives() (map[string]string, error) {
+	// <refname> NUL <trailers> NUL, where the trailers span lines
+	out, err := runCommandTrimmedOutput("git", "for-each-ref", "--format=%(refname)%00%(trailers:only,unfold)%00", branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	directives := map[string]string{}
+	for _, record := range strings.Split(out, "\x00\n") {
+		fields := strings.SplitN(record, "\x00", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		for _, line := range strings.Split(fields[1], "\n") {
+			if directive := parseDirective(line); directive != "" {
+				directives[strings.TrimPrefix(fields[0], branchPrefix)] = directive
+			}
+		}
+	}
+	return directives, nil
+}
+
+// parseDirective returns the directive given by a trailer line, if any
+func parseDirective(trailer string) string {
+	key, value, ok := strings.Cut(trailer, ":")
+	if !ok {
+		return ""
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "branch-cleanup":
+		switch value {
+		case "keep", "no", "never":
+			return directiveKeep
+		case "delete", "yes":
+			return directiveDelete
+		}
+	case "cleanup-after-merge":
+		if isTruthy(value) {
+			return directiveDelete
+		}
+		switch value {
+		case "no", "false", "0", "off":
+			return directiveKeep
+		}
+	}
+	return ""
+}
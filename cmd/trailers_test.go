@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		trailer  string
+		expected string
+	}{
+		{"Branch-cleanup: keep", directiveKeep},
+		{"branch-cleanup: KEEP", directiveKeep},
+		{"Branch-Cleanup:never", directiveKeep},
+		{"Branch-cleanup: no", directiveKeep},
+		{"Branch-cleanup: delete", directiveDelete},
+		{"Branch-cleanup: yes", directiveDelete},
+		{"  Branch-cleanup :  delete  ", directiveDelete},
+		{"Branch-cleanup: maybe", ""},
+		{"Branch-cleanup:", ""},
+		{"Cleanup-after-merge: yes", directiveDelete},
+		{"Cleanup-after-merge: true", directiveDelete},
+		{"Cleanup-after-merge: 1", directiveDelete},
+		{"Cleanup-after-merge: on", directiveDelete},
+		{"Cleanup-after-merge: no", directiveKeep},
+		{"Cleanup-after-merge: false", directiveKeep},
+		{"Cleanup-after-merge: off", directiveKeep},
+		{"Cleanup-after-merge: later", ""},
+		{"Signed-off-by: Jane <jane@example.com>", ""},
+		{"Branch-cleanup keep", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := parseDirective(test.trailer); got != test.expected {
+			t.Errorf("parseDirective(%q) = %q, expected %q", test.trailer, got, test.expected)
+		}
+	}
+}
+
+func TestGetBranchDirectives(t *testing.T) {
+	r := newTestRepo(t)
+	branches := map[string]string{
+		"kept":     "Keep it\n\nBranch-cleanup: keep\n",
+		"deleted":  "Delete it\n\nSigned-off-by: test <test@example.com>\nCleanup-after-merge: yes\n",
+		"last":     "Changed my mind\n\nBranch-cleanup: keep\nBranch-cleanup: delete\n",
+		"folded":   "Folded\n\nBranch-cleanup:\n delete\n",
+		"body":     "Only mentioned\n\nBranch-cleanup: keep is what I'd like\n\nbut not a trailer\n",
+		"untagged": "Nothing to say\n",
+	}
+	for branch, message := range branches {
+		r.git("checkout", "--quiet", "-b", branch, "main")
+		r.git("commit", "--quiet", "--allow-empty", "-m", message)
+	}
+	r.git("checkout", "--quiet", "main")
+	r.enter()
+
+	directives, err := getBranchDirectives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"kept": directiveKeep, "deleted": directiveDelete, "last": directiveDelete, "folded": directiveDelete}
+	for branch := range branches {
+		if directives[branch] != expected[branch] {
+			t.Errorf("%s: directive %q, expected %q", branch, directives[branch], expected[branch])
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+)
+
+// verifyUnchanged checks, right before a branch is deleted, that what it was
+// classified by still holds: that the branch is still at the tip which was
+// analyzed, and that what it was merged as is still part of the base (which a
+// force-push to the base could have undone since)
+func (rs *runState) verifyUnchanged(b *BranchReport) error {
+	if b.PotentialMerge == nil || b.TipSha == "" {
+		return nil // classified without analyzing its history, e.g. upstream-gone
+	}
+	tip, err := getGitRevParse(branchRef(b.Branch))
+	if err != nil {
+		return fmt.Errorf("it no longer exists")
+	}
+	if tip != b.TipSha {
+		return fmt.Errorf("it moved from %s to %s since it was analyzed", b.TipSha, tip)
+	}
+
+	merged := b.MatchedSha
+	if b.Merged {
+		merged = b.TipSha
+	}
+	if merged == "" {
+		return nil
+	}
+	base, err := resolveBase(rs.baseName)
+	if err != nil {
+		return fmt.Errorf("%s can no longer be resolved: %w", rs.baseName, err)
+	}
+	if !isAncestor(merged, base) {
+		return fmt.Errorf("%s is no longer part of %s (was it force-pushed?)", merged, rs.baseName)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Age is a duration which may also be given in days, weeks, months, or years,
+// e.g. 90d, 2w, 6mo, or 1y, the natural units for the age of commits
+type Age time.Duration
+
+var ageRegexp = regexp.MustCompile(`^(\d+)\s*(d|days?|w|weeks?|mo|months?|y|years?)$`)
+
+func (a *Age) UnmarshalFlag(value string) error {
+	value = strings.TrimSpace(value)
+	if m := ageRegexp.FindStringSubmatch(value); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return err
+		}
+		day := 24 * time.Hour
+		unit := day
+		switch m[2][0] {
+		case 'w':
+			unit = 7 * day
+		case 'm':
+			unit = 30 * day
+		case 'y':
+			unit = 365 * day
+		}
+		*a = Age(time.Duration(n) * unit)
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid age %q; expected e.g. 90d, 2w, 6mo, 1y, or 72h", value)
+	}
+	*a = Age(d)
+	return nil
+}
+
+// matchWindow limits how old a base commit can be to match a branch (0 for no limit)
+var matchWindow time.Duration
+
+// getCommitTimes returns the commit time of each commit between start and end
+// (with the same range as getCommits)
+func getCommitTimes(start, end string) (map[string]time.Time, error) {
+	lines, err := runCommandSplitLines("git", "log", "--format=format:%H %ct", start+".."+end, "--")
+	if err != nil {
+		return nil, err
+	}
+	times := map[string]time.Time{}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+		t, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		times[parts[0]] = time.Unix(t, 0)
+	}
+	return times, nil
+}
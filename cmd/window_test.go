@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgeUnmarshalFlag(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"90d", 90 * day},
+		{"1 day", day},
+		{"3days", 3 * day},
+		{"2w", 14 * day},
+		{"1 week", 7 * day},
+		{"6mo", 180 * day},
+		{"2 months", 60 * day},
+		{"1y", 365 * day},
+		{"2years", 730 * day},
+		{" 0d ", 0},
+		{"72h", 72 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, test := range tests {
+		var a Age
+		if err := a.UnmarshalFlag(test.value); err != nil {
+			t.Errorf("UnmarshalFlag(%q): %v", test.value, err)
+			continue
+		}
+		if time.Duration(a) != test.expected {
+			t.Errorf("UnmarshalFlag(%q) = %v, expected %v", test.value, time.Duration(a), test.expected)
+		}
+	}
+
+	for _, value := range []string{"", "d", "90", "1m2", "-3d", "1.5w", "3 fortnights", "1yr"} {
+		var a Age
+		if err := a.UnmarshalFlag(value); err == nil {
+			t.Errorf("UnmarshalFlag(%q) = %v, expected an error", value, time.Duration(a))
+		}
+	}
+}
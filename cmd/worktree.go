@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// getCheckedOutElsewhere returns a map of branch name to where else it is in
+// use: the path of a linked worktree which has it checked out, or the symbolic
+// ref which points at it. Symbolic refs under refs/heads are included too,
+// since deleting them would break whatever relies on the alias.
+func getCheckedOutElsewhere(currentBranch string) (map[string]string, error) {
+	inUse := map[string]string{}
+
+	lines, err := runCommandSplitLines("git", "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	var worktree string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "worktree ") {
+			worktree = strings.TrimPrefix(line, "worktree ")
+		}
+		if strings.HasPrefix(line, "branch ") {
+			branch := strings.TrimPrefix(strings.TrimPrefix(line, "branch "), branchPrefix)
+			if branch != currentBranch {
+				inUse[branch] = fmt.Sprintf(T("worktree %s"), worktree)
+			}
+		}
+	}
+
+	lines, err = runCommandSplitLines("git", "for-each-ref", "--format=%(refname) %(symref)", branchPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+		symref := strings.TrimPrefix(parts[0], branchPrefix)
+		inUse[symref] = fmt.Sprintf(T("symbolic ref to %s"), parts[1])
+		if target := strings.TrimPrefix(parts[1], branchPrefix); target != parts[1] {
+			inUse[target] = fmt.Sprintf(T("symbolic ref %s"), parts[0])
+		}
+	}
+	return inUse, nil
+}
@@ -5,6 +5,7 @@ go 1.20
 require (
 	github.com/hyperjumptech/beda v1.1.0
 	github.com/jessevdk/go-flags v1.5.0
+	golang.org/x/text v0.14.0
 )
 
-require golang.org/x/sys v0.0.0-20210320140829-1e4c9ba3b0c4 // indirect
+require golang.org/x/sys v0.5.0 // indirect